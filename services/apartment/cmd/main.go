@@ -1,13 +1,25 @@
 package main
 
 import (
+	"airbnb-clone/apt/internal/adapters/consumer"
 	httpserver "airbnb-clone/apt/internal/adapters/http_server"
+	"airbnb-clone/apt/internal/adapters/publisher"
 	"airbnb-clone/apt/internal/adapters/repository"
 	"airbnb-clone/apt/internal/config"
+	"airbnb-clone/apt/internal/domain/entity"
 	"airbnb-clone/apt/internal/domain/service"
+	"airbnb-clone/shared/authclient"
+	"airbnb-clone/shared/middleware"
+	"airbnb-clone/shared/outbox"
+	"airbnb-clone/shared/storage"
 
+	"context"
+	"expvar"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,26 +35,101 @@ func main() {
 	log := createLogger(cfg.Env)
 	log.Info("apartment app just started")
 
-	aptRepo, err := repository.New(cfg)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := repository.NewPostgresDB(cfg)
 	if err != nil {
 		log.Error("failed to setup database connection")
 		os.Exit(1)
 	}
 
-	aptService := service.NewApartmentService(aptRepo, "services/apartment/uploads", log)
-	r := setUpHttpServer(log, aptService)
+	aptPublisher := publisher.NewKafkaPublisher(cfg.Kafka.Brokers, cfg.Kafka.ApartmentTopic)
+	defer aptPublisher.Close()
+
+	relay := outbox.NewRelay[entity.ApartmentEvent](outbox.NewGormStore(db), aptPublisher, outbox.Config{
+		PollInterval:   cfg.Outbox.PollInterval,
+		BatchSize:      cfg.Outbox.BatchSize,
+		PublishTimeout: cfg.Outbox.PublishTimeout,
+		MaxBackoff:     cfg.Outbox.MaxBackoff,
+	}, log)
+	go relay.Run(ctx)
+
+	availabilityService := service.NewAvailabilityService(repository.NewAvailabilityRepository(db), log)
+	bookingConsumer := consumer.NewBookingConsumer(cfg.Kafka, availabilityService, log)
+	defer bookingConsumer.Close()
+	go bookingConsumer.Run(ctx)
+
+	blobs, err := storage.New(ctx, storage.Config{
+		Backend:   cfg.Storage.Backend,
+		LocalDir:  cfg.Storage.LocalDir,
+		PublicURL: cfg.Storage.PublicURL,
+		S3: storage.S3Config{
+			Endpoint:  cfg.Storage.S3.Endpoint,
+			AccessKey: cfg.Storage.S3.AccessKey,
+			SecretKey: cfg.Storage.S3.SecretKey,
+			Bucket:    cfg.Storage.S3.Bucket,
+			Region:    cfg.Storage.S3.Region,
+			UseSSL:    cfg.Storage.S3.UseSSL,
+		},
+	})
+	if err != nil {
+		log.Error("failed to setup blob storage", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	aptService := service.NewApartmentService(repository.New(db), blobs, log)
+	jwks := middleware.NewJWKSCache(cfg.Auth.JWKSURL, cfg.Auth.JWKSCacheTTL)
+	validator := middleware.NewTokenValidator(jwks, cfg.Auth.Issuer, cfg.Auth.Audience, cfg.Auth.ClockSkew)
+	if cfg.Auth.GRPC.Address != "" {
+		authClient, err := authclient.New(authclient.Config{
+			Address:    cfg.Auth.GRPC.Address,
+			CAFile:     cfg.Auth.GRPC.CAFile,
+			ServerName: cfg.Auth.GRPC.ServerName,
+			CertFile:   cfg.Auth.GRPC.CertFile,
+			KeyFile:    cfg.Auth.GRPC.KeyFile,
+			Timeout:    cfg.Auth.GRPC.Timeout,
+			CacheTTL:   cfg.Auth.GRPC.CacheTTL,
+			CacheSize:  cfg.Auth.GRPC.CacheSize,
+		})
+		if err != nil {
+			log.Error("failed to setup auth client", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		defer authClient.Close()
+		validator.UseIntrospector(authClient)
+	}
+	if cfg.AdminAddress != "" {
+		go serveAdmin(log, cfg.AdminAddress)
+	}
+
+	r := setUpHttpServer(log, aptService, validator)
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Error("invalid trusted proxies", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 	if err := r.Run(cfg.Address); err != nil {
 		log.Error("Failed to start server:", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
 	}
 }
 
-func setUpHttpServer(log *slog.Logger, aptService service.ApartmentService) *gin.Engine {
+func setUpHttpServer(log *slog.Logger, aptService service.ApartmentService, validator *middleware.TokenValidator) *gin.Engine {
 	r := gin.Default()
 	aptController := httpserver.NewProfileController(log, aptService)
-	httpserver.SetupProfileRoutes(r, aptController)
+	httpserver.SetupProfileRoutes(r, aptController, validator)
 	return r
 }
 
+// serveAdmin serves the runtime metrics on a listener of its own, so that they
+// are not exposed with the API
+func serveAdmin(log *slog.Logger, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	if err := http.ListenAndServe(address, mux); err != nil {
+		log.Error("admin server stopped", slog.String("error", err.Error()))
+	}
+}
+
 func createLogger(env string) *slog.Logger {
 	var log *slog.Logger
 
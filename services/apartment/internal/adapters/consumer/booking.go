@@ -0,0 +1,58 @@
+package consumer
+
+import (
+	"airbnb-clone/apt/internal/config"
+	"airbnb-clone/apt/internal/domain/entity"
+	"airbnb-clone/apt/internal/domain/service"
+	"airbnb-clone/shared/kafkaconsumer"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// NewBookingConsumer keeps the booked periods in sync with the booking events.
+// Messages that cannot be applied end up in the dead-letter topic
+func NewBookingConsumer(cfg config.Kafka, availability service.AvailabilityService, log *slog.Logger) *kafkaconsumer.Consumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: cfg.Brokers,
+		GroupID: cfg.GroupID,
+		Topic:   cfg.BookingTopic,
+	})
+
+	deadLetter := &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Brokers...),
+		Topic:                  cfg.DeadLetterTopic,
+		Balancer:               &kafka.Hash{},
+		AllowAutoTopicCreation: true,
+	}
+
+	handler := &bookingHandler{availability: availability}
+	return kafkaconsumer.New(reader, deadLetter, handler.handle, kafkaconsumer.Config{
+		MaxRetries:   cfg.MaxRetries,
+		RetryBackoff: cfg.RetryBackoff,
+		MaxBackoff:   cfg.MaxBackoff,
+	}, log)
+}
+
+type bookingHandler struct {
+	availability service.AvailabilityService
+}
+
+func (h *bookingHandler) handle(_ context.Context, msg kafka.Message) error {
+	var event entity.BookingEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return kafkaconsumer.Permanent(err)
+	}
+
+	if err := h.availability.SyncBooking(&event); err != nil {
+		if errors.Is(err, service.ErrInvalidEvent) {
+			return kafkaconsumer.Permanent(err)
+		}
+		return err
+	}
+
+	return nil
+}
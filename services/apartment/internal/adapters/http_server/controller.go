@@ -1,11 +1,13 @@
 package httpserver
 
 import (
-	"airbnb-clone/apt/internal/adapters/http_server/middleware"
 	"airbnb-clone/apt/internal/domain/entity"
 	"airbnb-clone/apt/internal/domain/service"
+	"airbnb-clone/shared/middleware"
+	"airbnb-clone/shared/storage"
 	"errors"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -15,8 +17,15 @@ type ApartmentController interface {
 	CreateApartment(ctx *gin.Context)
 	ServeImages(ctx *gin.Context)
 	GetApartment(ctx *gin.Context)
+	SearchApartments(ctx *gin.Context)
 	DeleteApartment(ctx *gin.Context)
+	RemoveApartment(ctx *gin.Context)
 	UpdateApartment(ctx *gin.Context)
+	AddImages(ctx *gin.Context)
+	DeleteImage(ctx *gin.Context)
+	ReorderImages(ctx *gin.Context)
+	SetCoverImage(ctx *gin.Context)
+	UpdateImage(ctx *gin.Context)
 }
 
 type apartmentController struct {
@@ -52,6 +61,10 @@ func (c *apartmentController) CreateApartment(ctx *gin.Context) {
 
 	apt, err := c.apartmentService.CreateApartment(&req, userID, files)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidImage) || errors.Is(err, service.ErrImageTooLarge) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		log.Error("failed to create an apartment", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -84,12 +97,42 @@ func (c *apartmentController) GetApartment(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, aptResponse)
 }
 
+func (c *apartmentController) SearchApartments(ctx *gin.Context) {
+	const fn = "adapters.controller.SearchApartments"
+	log := c.log.With(slog.String("fn", fn))
+
+	var req entity.SearchApartmentsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := c.apartmentService.SearchApartments(&req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidInput) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search parameters"})
+			return
+		}
+		log.Error("failed to search apartments", slog.String("error", err.Error()))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
 func (c *apartmentController) DeleteApartment(ctx *gin.Context) {
-	const fn = "adapters.controller.GetYourProfile"
+	const fn = "adapters.controller.DeleteApartment"
 	log := c.log.With(
 		slog.String("fn", fn),
 	)
 
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	aptID := ctx.Param("id")
 	if aptID == "" {
 		log.Error("apt id was not provided")
@@ -97,11 +140,41 @@ func (c *apartmentController) DeleteApartment(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.apartmentService.DeleteApartment(aptID); err != nil {
+	if err := c.apartmentService.DeleteApartment(aptID, userID); err != nil {
 		if errors.Is(err, service.ErrAptNotFound) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Apartment with provided ID not found"})
 			return
 		}
+		if errors.Is(err, service.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Only the host can delete the apartment"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
+// RemoveApartment lets moderators take down any listing
+func (c *apartmentController) RemoveApartment(ctx *gin.Context) {
+	const fn = "adapters.controller.RemoveApartment"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	aptID := ctx.Param("id")
+	if aptID == "" {
+		log.Error("apt id was not provided")
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Apartment ID was not provided"})
+		return
+	}
+
+	if err := c.apartmentService.RemoveApartment(aptID); err != nil {
+		if errors.Is(err, service.ErrAptNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Apartment with provided ID not found"})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -113,28 +186,56 @@ func (c *apartmentController) UpdateApartment(ctx *gin.Context) {
 	const fn = "adapters.controller.UpdateApartment"
 	log := c.log.With(slog.String("fn", fn))
 
-	id := ctx.Param("id")
-	if id == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing apartment id"})
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	form, err := ctx.MultipartForm()
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid form data"})
+	id := ctx.Param("id")
+	if id == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing apartment id"})
 		return
 	}
 
-	files := form.File["images"]
-	updates := make(map[string]interface{})
-	for key, values := range form.Value {
-		if len(values) > 0 {
-			updates[key] = values[0]
+	// PATCH accepts a JSON body, PUT a multipart form that may also carry images
+	var (
+		req   entity.UpdateApartmentRequest
+		files []*multipart.FileHeader
+	)
+	if ctx.ContentType() == gin.MIMEJSON {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		if err := ctx.ShouldBind(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if form, err := ctx.MultipartForm(); err == nil {
+			files = form.File["images"]
 		}
 	}
 
-	apt, err := c.apartmentService.UpdateApartment(id, updates, files)
+	apt, err := c.apartmentService.UpdateApartment(id, userID, &req, files)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidInput) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or empty apartment update"})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidImage) || errors.Is(err, service.ErrImageTooLarge) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrAptNotFound) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Apartment with provided ID not found"})
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Only the host can update the apartment"})
+			return
+		}
 		log.Error("failed to update apartment", slog.String("error", err.Error()))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -144,6 +245,26 @@ func (c *apartmentController) UpdateApartment(ctx *gin.Context) {
 }
 
 func (c *apartmentController) ServeImages(ctx *gin.Context) {
-	filename := "services/apartment/uploads/" + ctx.Param("filename")
-	ctx.File(filename)
+	const fn = "adapters.controller.ServeImages"
+	log := c.log.With(slog.String("fn", fn))
+
+	name := ctx.Param("filename")
+	if !storage.ValidImageName(name) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image name"})
+		return
+	}
+
+	blob, err := c.apartmentService.OpenImage(name)
+	if err != nil {
+		if errors.Is(err, service.ErrImageNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		log.Error("failed to open image", slog.String("error", err.Error()))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer blob.Body.Close()
+
+	storage.ServeBlob(ctx, blob, storage.ImmutableCacheControl)
 }
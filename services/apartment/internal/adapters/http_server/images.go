@@ -0,0 +1,142 @@
+package httpserver
+
+import (
+	"airbnb-clone/apt/internal/domain/entity"
+	"airbnb-clone/apt/internal/domain/service"
+	"airbnb-clone/shared/middleware"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (c *apartmentController) AddImages(ctx *gin.Context) {
+	const fn = "adapters.controller.AddImages"
+	log := c.log.With(slog.String("fn", fn))
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	form, err := ctx.MultipartForm()
+	if err != nil || len(form.File["images"]) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "No images were provided"})
+		return
+	}
+
+	images, err := c.apartmentService.AddImages(ctx.Param("id"), userID, form.File["images"], form.Value["captions"])
+	if err != nil {
+		c.imageError(ctx, log, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, images)
+}
+
+func (c *apartmentController) DeleteImage(ctx *gin.Context) {
+	const fn = "adapters.controller.DeleteImage"
+	log := c.log.With(slog.String("fn", fn))
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := c.apartmentService.DeleteImage(ctx.Param("id"), userID, ctx.Param("imageId")); err != nil {
+		c.imageError(ctx, log, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
+func (c *apartmentController) ReorderImages(ctx *gin.Context) {
+	const fn = "adapters.controller.ReorderImages"
+	log := c.log.With(slog.String("fn", fn))
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req entity.ReorderImagesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	images, err := c.apartmentService.ReorderImages(ctx.Param("id"), userID, req.ImageIDs)
+	if err != nil {
+		c.imageError(ctx, log, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
+
+func (c *apartmentController) SetCoverImage(ctx *gin.Context) {
+	const fn = "adapters.controller.SetCoverImage"
+	log := c.log.With(slog.String("fn", fn))
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	images, err := c.apartmentService.SetCoverImage(ctx.Param("id"), userID, ctx.Param("imageId"))
+	if err != nil {
+		c.imageError(ctx, log, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
+
+func (c *apartmentController) UpdateImage(ctx *gin.Context) {
+	const fn = "adapters.controller.UpdateImage"
+	log := c.log.With(slog.String("fn", fn))
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req entity.UpdateImageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	image, err := c.apartmentService.UpdateImageCaption(ctx.Param("id"), userID, ctx.Param("imageId"), req.Caption)
+	if err != nil {
+		c.imageError(ctx, log, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, image)
+}
+
+func (c *apartmentController) imageError(ctx *gin.Context, log *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, service.ErrAptNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Apartment with provided ID not found"})
+	case errors.Is(err, service.ErrImageNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Image with provided ID not found"})
+	case errors.Is(err, service.ErrForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Only the host can manage apartment images"})
+	case errors.Is(err, service.ErrInvalidInput):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image request"})
+	case errors.Is(err, service.ErrInvalidImage), errors.Is(err, service.ErrImageTooLarge):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Error("failed to manage images", slog.String("error", err.Error()))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,19 +1,34 @@
 package httpserver
 
 import (
-	"airbnb-clone/apt/internal/adapters/http_server/middleware"
+	"airbnb-clone/shared/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupProfileRoutes(r *gin.Engine, apartmentController ApartmentController) {
+func SetupProfileRoutes(r *gin.Engine, apartmentController ApartmentController, validator *middleware.TokenValidator) {
 	authGroup := r.Group("/")
-	authGroup.Use(middleware.AuthMiddleware())
+	authGroup.Use(middleware.AuthMiddleware(validator))
 	{
-		authGroup.POST("/apartment", apartmentController.CreateApartment)
+		authGroup.POST("/apartment", middleware.RequireVerifiedEmail(),
+			middleware.RequirePermission(middleware.PermissionCreateListing), apartmentController.CreateApartment)
+		authGroup.PUT("/apartment/:id", apartmentController.UpdateApartment)
+		authGroup.PATCH("/apartment/:id", apartmentController.UpdateApartment)
+		authGroup.DELETE("/apartment/:id", apartmentController.DeleteApartment)
+		authGroup.POST("/apartment/:id/images", apartmentController.AddImages)
+		authGroup.PUT("/apartment/:id/images/order", apartmentController.ReorderImages)
+		authGroup.PUT("/apartment/:id/images/:imageId/cover", apartmentController.SetCoverImage)
+		authGroup.PATCH("/apartment/:id/images/:imageId", apartmentController.UpdateImage)
+		authGroup.DELETE("/apartment/:id/images/:imageId", apartmentController.DeleteImage)
 	}
-	r.PUT("/apartment/:id", apartmentController.UpdateApartment)
+
+	adminGroup := r.Group("/admin")
+	adminGroup.Use(middleware.AuthMiddleware(validator), middleware.RequirePermission(middleware.PermissionModerateListings))
+	{
+		adminGroup.DELETE("/apartment/:id", apartmentController.RemoveApartment)
+	}
+
 	r.GET("/apartment/:id", apartmentController.GetApartment)
-	r.DELETE("/apartment/:id", apartmentController.DeleteApartment)
+	r.GET("/apartments", apartmentController.SearchApartments)
 	r.GET("/uploads/:filename", apartmentController.ServeImages)
 }
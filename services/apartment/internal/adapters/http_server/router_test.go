@@ -0,0 +1,173 @@
+package httpserver
+
+import (
+	"airbnb-clone/apt/internal/adapters/repository"
+	"airbnb-clone/apt/internal/domain/entity"
+	"airbnb-clone/apt/internal/domain/service"
+	"airbnb-clone/shared/middleware"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	testKID      = "test-key"
+	testIssuer   = "airbnb-clone-auth"
+	testAudience = "airbnb-clone"
+	testAptID    = "apt-1"
+	testHostID   = "host-1"
+)
+
+// fakeApartmentRepository keeps apartments in memory. Only the methods used
+// by updates and deletes are implemented
+type fakeApartmentRepository struct {
+	repository.ApartmentRepository
+	apartments map[string]*entity.Apartment
+}
+
+func (r *fakeApartmentRepository) GetApartment(id string) (*entity.Apartment, error) {
+	apt, ok := r.apartments[id]
+	if !ok {
+		return nil, repository.ErrAptNotFound
+	}
+	copied := *apt
+	return &copied, nil
+}
+
+func (r *fakeApartmentRepository) DeleteApartmentByID(id string) error {
+	delete(r.apartments, id)
+	return nil
+}
+
+func (r *fakeApartmentRepository) UpdateApartmentFields(id string, updates map[string]interface{}) error {
+	if title, ok := updates["title"].(string); ok {
+		r.apartments[id].Title = title
+	}
+	return nil
+}
+
+func (r *fakeApartmentRepository) AddOutboxMessage(*entity.OutboxMessage) error {
+	return nil
+}
+
+func (r *fakeApartmentRepository) WithinTransaction(fn func(repo repository.ApartmentRepository) error) error {
+	return fn(r)
+}
+
+// newTestRouter serves the apartment routes with tokens checked against a
+// JWKS served by an httptest server
+func newTestRouter(t *testing.T, key *rsa.PrivateKey, repo repository.ApartmentRepository) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testKID,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(jwks.Close)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	validator := middleware.NewTokenValidator(middleware.NewJWKSCache(jwks.URL, time.Minute), testIssuer, testAudience, 0)
+	controller := NewProfileController(log, service.NewApartmentService(repo, nil, log))
+
+	r := gin.New()
+	SetupProfileRoutes(r, controller, validator)
+	return r
+}
+
+func signAccessToken(t *testing.T, key *rsa.PrivateKey, userID string) string {
+	t.Helper()
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, middleware.Claims{
+		UserID: userID,
+		Type:   middleware.TokenTypeAccess,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    testIssuer,
+			Audience:  testAudience,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(time.Minute).Unix(),
+		},
+	})
+	token.Header["kid"] = testKID
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func TestApartmentOwnerOnlyRoutes(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		userID string
+		want   int
+	}{
+		{name: "owner can put", method: http.MethodPut, userID: testHostID, want: http.StatusOK},
+		{name: "non-owner cannot put", method: http.MethodPut, userID: "guest-1", want: http.StatusForbidden},
+		{name: "anonymous cannot put", method: http.MethodPut, want: http.StatusUnauthorized},
+		{name: "owner can patch", method: http.MethodPatch, userID: testHostID, want: http.StatusOK},
+		{name: "non-owner cannot patch", method: http.MethodPatch, userID: "guest-1", want: http.StatusForbidden},
+		{name: "anonymous cannot patch", method: http.MethodPatch, want: http.StatusUnauthorized},
+		{name: "owner can delete", method: http.MethodDelete, userID: testHostID, want: http.StatusOK},
+		{name: "non-owner cannot delete", method: http.MethodDelete, userID: "guest-1", want: http.StatusForbidden},
+		{name: "anonymous cannot delete", method: http.MethodDelete, want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeApartmentRepository{apartments: map[string]*entity.Apartment{
+				testAptID: {ID: testAptID, HostID: testHostID, Title: "Old title"},
+			}}
+			r := newTestRouter(t, key, repo)
+
+			var body io.Reader
+			if tt.method != http.MethodDelete {
+				body = strings.NewReader(`{"title":"New title"}`)
+			}
+			req := httptest.NewRequest(tt.method, "/apartment/"+testAptID, body)
+			req.Header.Set("Content-Type", gin.MIMEJSON)
+			if tt.userID != "" {
+				req.Header.Set("Authorization", "Bearer "+signAccessToken(t, key, tt.userID))
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.want, w.Body.String())
+			}
+
+			apt, stillThere := repo.apartments[testAptID]
+			modified := !stillThere || apt.Title != "Old title"
+			if modified != (tt.want == http.StatusOK) {
+				t.Errorf("apartment modified = %v after status %d", modified, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package publisher
+
+import (
+	"airbnb-clone/apt/internal/domain/entity"
+	"context"
+	"sync"
+)
+
+// InMemoryPublisher keeps published events in memory. It is meant for tests
+// and for running the service without a broker
+type InMemoryPublisher struct {
+	mu     sync.Mutex
+	events []entity.ApartmentEvent
+}
+
+func NewInMemoryPublisher() *InMemoryPublisher {
+	return &InMemoryPublisher{}
+}
+
+func (p *InMemoryPublisher) Publish(_ context.Context, event *entity.ApartmentEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, *event)
+	return nil
+}
+
+// Events returns a copy of everything published so far
+func (p *InMemoryPublisher) Events() []entity.ApartmentEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	events := make([]entity.ApartmentEvent, len(p.events))
+	copy(events, p.events)
+	return events
+}
+
+func (p *InMemoryPublisher) Close() error {
+	return nil
+}
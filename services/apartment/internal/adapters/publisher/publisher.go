@@ -0,0 +1,11 @@
+package publisher
+
+import (
+	"airbnb-clone/apt/internal/domain/entity"
+	"context"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event *entity.ApartmentEvent) error
+	Close() error
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"airbnb-clone/apt/internal/domain/entity"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AvailabilityRepository interface {
+	UpsertBookedPeriod(period *entity.BookedPeriod) error
+}
+
+type availabilityStorage struct {
+	db *gorm.DB
+}
+
+func NewAvailabilityRepository(db *gorm.DB) AvailabilityRepository {
+	return &availabilityStorage{db: db}
+}
+
+// UpsertBookedPeriod stores the period unless a newer event was already applied,
+// so redelivered or reordered messages never resurrect a cancelled booking
+func (s *availabilityStorage) UpsertBookedPeriod(period *entity.BookedPeriod) error {
+	const fn = "adapters.repository.UpsertBookedPeriod"
+
+	result := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "booking_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"apartment_id", "check_in", "check_out", "active", "occurred_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "booked_periods.occurred_at < excluded.occurred_at"},
+		}},
+	}).Create(period)
+	if result.Error != nil {
+		return fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return nil
+}
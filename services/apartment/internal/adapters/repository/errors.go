@@ -3,5 +3,6 @@ package repository
 import "errors"
 
 var (
-	ErrAptNotFound = errors.New("apartments with provided ID was not found")
+	ErrAptNotFound   = errors.New("apartments with provided ID was not found")
+	ErrImageNotFound = errors.New("image with provided ID was not found")
 )
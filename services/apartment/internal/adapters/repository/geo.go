@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"airbnb-clone/apt/internal/domain/entity"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+// kmPerDegree is the length of one degree of latitude
+const kmPerDegree = 111.045
+
+// haversineSQL is the great-circle distance in km from (?, ?) to the apartment.
+// Placeholders are latitude, latitude, longitude, see haversineArgs
+const haversineSQL = `(2 * 6371.0 * ASIN(LEAST(1, SQRT(
+	POWER(SIN(RADIANS(latitude - ?) / 2), 2) +
+	COS(RADIANS(?)) * COS(RADIANS(latitude)) * POWER(SIN(RADIANS(longitude - ?) / 2), 2)))))`
+
+func haversineArgs(point *entity.GeoPoint) []interface{} {
+	return []interface{}{point.Latitude, point.Latitude, point.Longitude}
+}
+
+// applyRadius keeps apartments within radiusKm of point and selects the
+// distance as distance_km. A bounding box prefilter lets postgres use the
+// (latitude, longitude) index before the exact haversine check
+func applyRadius(query *gorm.DB, point *entity.GeoPoint, radiusKm float64) *gorm.DB {
+	query = query.Select("apartments.*, "+haversineSQL+" AS distance_km", haversineArgs(point)...)
+
+	latDelta := radiusKm / kmPerDegree
+	minLat, maxLat := point.Latitude-latDelta, point.Latitude+latDelta
+	query = query.Where("latitude BETWEEN ? AND ?", math.Max(minLat, -90), math.Min(maxLat, 90))
+
+	// close to the poles every longitude may be within the radius
+	if minLat > -90 && maxLat < 90 {
+		lngDelta := radiusKm / (kmPerDegree * math.Cos(point.Latitude*math.Pi/180))
+		if lngDelta < 180 {
+			query = applyLongitudeRange(query, wrapLongitude(point.Longitude-lngDelta), wrapLongitude(point.Longitude+lngDelta))
+		}
+	}
+
+	return query.Where(haversineSQL+" <= ?", append(haversineArgs(point), radiusKm)...)
+}
+
+func applyBoundingBox(query *gorm.DB, box *entity.BoundingBox) *gorm.DB {
+	query = query.Where("latitude BETWEEN ? AND ?", box.MinLatitude, box.MaxLatitude)
+	return applyLongitudeRange(query, box.MinLongitude, box.MaxLongitude)
+}
+
+// applyLongitudeRange treats minLng > maxLng as a range crossing the antimeridian
+func applyLongitudeRange(query *gorm.DB, minLng, maxLng float64) *gorm.DB {
+	if minLng <= maxLng {
+		return query.Where("longitude BETWEEN ? AND ?", minLng, maxLng)
+	}
+	return query.Where("(longitude >= ? OR longitude <= ?)", minLng, maxLng)
+}
+
+func wrapLongitude(lng float64) float64 {
+	switch {
+	case lng > 180:
+		return lng - 360
+	case lng < -180:
+		return lng + 360
+	}
+	return lng
+}
@@ -18,15 +18,20 @@ type ApartmentRepository interface {
 	UpdateApartmentFields(id string, updates map[string]interface{}) error
 	AddImages(apartmentID string, images []entity.Image) error
 	GetApartmentImages(apartmentID string) ([]entity.Image, error)
-	DeleteApartmentImages(apartmentID string) error
+	GetImage(apartmentID string, imageID string) (*entity.Image, error)
+	UpdateImageFields(imageID string, updates map[string]interface{}) error
+	DeleteImage(imageID string) error
+	Search(filter *entity.ApartmentFilter) ([]entity.Apartment, error)
+	AddOutboxMessage(message *entity.OutboxMessage) error
+	WithinTransaction(fn func(repo ApartmentRepository) error) error
 }
 
 type storage struct {
 	db *gorm.DB
 }
 
-func New(cfg *config.Config) (ApartmentRepository, error) {
-	const fn = "adapters.repository.New"
+func NewPostgresDB(cfg *config.Config) (*gorm.DB, error) {
+	const fn = "adapters.repository.NewPostgresDB"
 	dsn := fmt.Sprintf("host=%s user=%s "+
 		"password=%s dbname=%s port=%d sslmode=disable",
 		cfg.PostgresConnect.Host, cfg.PostgresConnect.User, cfg.PostgresConnect.Password, cfg.PostgresConnect.DatabaseName, cfg.PostgresConnect.Port)
@@ -38,11 +43,27 @@ func New(cfg *config.Config) (ApartmentRepository, error) {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	err = db.AutoMigrate(&entity.Apartment{}, &entity.Image{})
+	err = db.AutoMigrate(&entity.Apartment{}, &entity.Image{}, &entity.OutboxMessage{}, &entity.BookedPeriod{})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
-	return &storage{db: db}, nil
+
+	if err := migrateSearchIndexes(db); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return db, nil
+}
+
+func New(db *gorm.DB) ApartmentRepository {
+	return &storage{db: db}
+}
+
+// WithinTransaction runs fn against a repository bound to a single transaction,
+// so apartment changes and their outbox messages are committed together
+func (s *storage) WithinTransaction(fn func(repo ApartmentRepository) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&storage{db: tx})
+	})
 }
 
 func (s *storage) CreateNewApartment(apartment *entity.Apartment) error {
@@ -59,7 +80,7 @@ func (s *storage) GetApartment(id string) (*entity.Apartment, error) {
 	const fn = "adapters.repository.GetApartment"
 	var apt entity.Apartment
 
-	result := s.db.Preload("Images").First(&apt, "id = ?", id)
+	result := s.db.Preload("Images", orderImages).First(&apt, "id = ?", id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return &entity.Apartment{}, ErrAptNotFound
@@ -109,16 +130,65 @@ func (s *storage) AddImages(apartmentID string, images []entity.Image) error {
 
 func (s *storage) GetApartmentImages(apartmentID string) ([]entity.Image, error) {
 	var images []entity.Image
-	err := s.db.Where("apartment_id = ?", apartmentID).Find(&images).Error
+	err := orderImages(s.db.Where("apartment_id = ?", apartmentID)).Find(&images).Error
 	return images, err
 }
 
-func (s *storage) DeleteApartmentImages(apartmentID string) error {
-	const fn = "adapters.repository.DeleteApartmentImages"
+func (s *storage) GetImage(apartmentID string, imageID string) (*entity.Image, error) {
+	const fn = "adapters.repository.GetImage"
+	var image entity.Image
+
+	result := s.db.First(&image, "id = ? AND apartment_id = ?", imageID, apartmentID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return &entity.Image{}, ErrImageNotFound
+		}
+
+		return &entity.Image{}, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return &image, nil
+}
+
+func (s *storage) UpdateImageFields(imageID string, updates map[string]interface{}) error {
+	const fn = "adapters.repository.UpdateImageFields"
+
+	result := s.db.Model(&entity.Image{}).Where("id = ?", imageID).Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrImageNotFound
+	}
+
+	return nil
+}
+
+func (s *storage) DeleteImage(imageID string) error {
+	const fn = "adapters.repository.DeleteImage"
 
-	result := s.db.Delete(&entity.Image{}, "apartment_id = ?", apartmentID)
+	result := s.db.Delete(&entity.Image{}, "id = ?", imageID)
 	if result.Error != nil {
 		return fmt.Errorf("%s: database error: %w", fn, result.Error)
 	}
+
+	if result.RowsAffected == 0 {
+		return ErrImageNotFound
+	}
+	return nil
+}
+
+func orderImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+func (s *storage) AddOutboxMessage(message *entity.OutboxMessage) error {
+	const fn = "adapters.repository.AddOutboxMessage"
+
+	result := s.db.Create(message)
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
 	return nil
 }
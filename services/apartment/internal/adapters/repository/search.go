@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"airbnb-clone/apt/internal/domain/entity"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// searchIndexes back the case-insensitive location filters used by Search
+var searchIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS idx_apartments_lower_city ON apartments (LOWER(city), price_per_night)",
+	"CREATE INDEX IF NOT EXISTS idx_apartments_lower_country ON apartments (LOWER(country))",
+	"CREATE INDEX IF NOT EXISTS idx_apartments_lat_lng ON apartments (latitude, longitude)",
+}
+
+func migrateSearchIndexes(db *gorm.DB) error {
+	for _, stmt := range searchIndexes {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search returns up to filter.Limit apartments ordered by filter.Sort, starting
+// right after filter.After. The (sort key, id) pair keeps the order stable
+func (s *storage) Search(filter *entity.ApartmentFilter) ([]entity.Apartment, error) {
+	const fn = "adapters.repository.Search"
+	var apartments []entity.Apartment
+
+	query := applyApartmentFilter(s.db.Model(&entity.Apartment{}), filter)
+	if filter.Near != nil {
+		query = applyRadius(query, filter.Near, filter.RadiusKm)
+	}
+	if filter.Box != nil {
+		query = applyBoundingBox(query, filter.Box)
+	}
+
+	switch filter.Sort {
+	case entity.SortDistance:
+		if filter.After != nil {
+			query = query.Where("("+haversineSQL+", id) > (?, ?)",
+				append(haversineArgs(filter.Near), filter.After.DistanceKm, filter.After.ID)...)
+		}
+		query = query.Order("distance_km ASC, id ASC")
+	case entity.SortPriceAsc:
+		if filter.After != nil {
+			query = query.Where("(price_per_night, id) > (?, ?)", filter.After.PricePerNight, filter.After.ID)
+		}
+		query = query.Order("price_per_night ASC, id ASC")
+	case entity.SortPriceDesc:
+		if filter.After != nil {
+			query = query.Where("(price_per_night, id) < (?, ?)", filter.After.PricePerNight, filter.After.ID)
+		}
+		query = query.Order("price_per_night DESC, id DESC")
+	default:
+		if filter.After != nil {
+			query = query.Where("(created_at, id) < (?, ?)", filter.After.CreatedAt, filter.After.ID)
+		}
+		query = query.Order("created_at DESC, id DESC")
+	}
+
+	result := query.Preload("Images", orderImages).Limit(filter.Limit).Find(&apartments)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return apartments, nil
+}
+
+func applyApartmentFilter(query *gorm.DB, filter *entity.ApartmentFilter) *gorm.DB {
+	if filter.City != "" {
+		query = query.Where("LOWER(city) = ?", strings.ToLower(filter.City))
+	}
+	if filter.Country != "" {
+		query = query.Where("LOWER(country) = ?", strings.ToLower(filter.Country))
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price_per_night >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price_per_night <= ?", *filter.MaxPrice)
+	}
+	if filter.MinGuests != nil {
+		query = query.Where("max_guests >= ?", *filter.MinGuests)
+	}
+	if filter.MinBedrooms != nil {
+		query = query.Where("bedroom_number >= ?", *filter.MinBedrooms)
+	}
+	if filter.AvailableFrom != nil && filter.AvailableTo != nil {
+		query = query.Where(`NOT EXISTS (
+			SELECT 1 FROM booked_periods bp
+			WHERE bp.apartment_id = apartments.id AND bp.active
+				AND bp.check_in < ? AND bp.check_out > ?)`, *filter.AvailableTo, *filter.AvailableFrom)
+	}
+
+	amenities := map[string]*bool{
+		"wifi":          filter.Wifi,
+		"parking":       filter.Parking,
+		"air_condition": filter.AirCondition,
+		"kitchen":       filter.Kitchen,
+		"pet_friendly":  filter.PetFriendly,
+	}
+	// sorted, so that the same filter always builds the same statement
+	for _, column := range slices.Sorted(maps.Keys(amenities)) {
+		if value := amenities[column]; value != nil {
+			query = query.Where(column+" = ?", *value)
+		}
+	}
+
+	return query
+}
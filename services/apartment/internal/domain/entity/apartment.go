@@ -7,7 +7,7 @@ type Apartment struct {
 	HostID        string  `gorm:"not null"`
 	Title         string  `gorm:"size:255;not null"`
 	Description   string  `gorm:"type:text"`
-	PricePerNight float64 `gorm:"not null"`
+	PricePerNight float64 `gorm:"not null;index"`
 
 	HouseNumber int    `gorm:"not null"`
 	Street      string `gorm:"size:255;not null"`
@@ -18,6 +18,8 @@ type Apartment struct {
 
 	Latitude  float64
 	Longitude float64
+	// DistanceKm is filled only by geo searches
+	DistanceKm *float64 `gorm:"->;-:migration"`
 
 	Wifi         bool `gorm:"default:false"`
 	Parking      bool `gorm:"default:false"`
@@ -25,10 +27,10 @@ type Apartment struct {
 	Kitchen      bool `gorm:"default:false"`
 	PetFriendly  bool `gorm:"default:false"`
 
-	MaxGuests     int       `gorm:"not null"`
+	MaxGuests     int       `gorm:"not null;index"`
 	BedroomNumber int       `gorm:"not null"`
 	Images        []Image   `gorm:"foreignKey:ApartmentID;constraint:OnDelete:CASCADE;"`
-	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	CreatedAt     time.Time `gorm:"autoCreateTime;index"`
 	UpdatedAt     time.Time `gorm:"autoCreateTime"`
 }
 
@@ -58,28 +60,28 @@ type CreateApartmentRequest struct {
 }
 
 type UpdateApartmentRequest struct {
-	Title         *string  `form:"title,omitempty"`
-	Description   *string  `form:"description,omitempty"`
-	PricePerNight *float64 `form:"price_per_night,omitempty"`
-
-	HouseNumber *int    `form:"house_number,omitempty"`
-	Street      *string `form:"street,omitempty"`
-	City        *string `form:"city,omitempty"`
-	State       *string `form:"state,omitempty"`
-	Country     *string `form:"country,omitempty"`
-	PostalCode  *string `form:"postal_code,omitempty"`
-
-	Latitude  *float64 `form:"latitude,omitempty"`
-	Longitude *float64 `form:"longitude,omitempty"`
-
-	Wifi         *bool `form:"wifi,omitempty"`
-	Parking      *bool `form:"parking,omitempty"`
-	AirCondition *bool `form:"air_condition,omitempty"`
-	Kitchen      *bool `form:"kitchen,omitempty"`
-	PetFriendly  *bool `form:"pet_friendly,omitempty"`
-
-	MaxGuests     *int `form:"max_guests,omitempty"`
-	BedroomNumber *int `form:"bedroom_number,omitempty"`
+	Title         *string  `form:"title,omitempty" json:"title,omitempty" binding:"omitempty,min=1,max=255"`
+	Description   *string  `form:"description,omitempty" json:"description,omitempty"`
+	PricePerNight *float64 `form:"price_per_night,omitempty" json:"price_per_night,omitempty" binding:"omitempty,gt=0"`
+
+	HouseNumber *int    `form:"house_number,omitempty" json:"house_number,omitempty" binding:"omitempty,gt=0"`
+	Street      *string `form:"street,omitempty" json:"street,omitempty" binding:"omitempty,min=1,max=255"`
+	City        *string `form:"city,omitempty" json:"city,omitempty" binding:"omitempty,min=1,max=100"`
+	State       *string `form:"state,omitempty" json:"state,omitempty" binding:"omitempty,max=100"`
+	Country     *string `form:"country,omitempty" json:"country,omitempty" binding:"omitempty,min=1,max=100"`
+	PostalCode  *string `form:"postal_code,omitempty" json:"postal_code,omitempty" binding:"omitempty,max=20"`
+
+	Latitude  *float64 `form:"latitude,omitempty" json:"latitude,omitempty" binding:"omitempty,gte=-90,lte=90"`
+	Longitude *float64 `form:"longitude,omitempty" json:"longitude,omitempty" binding:"omitempty,gte=-180,lte=180"`
+
+	Wifi         *bool `form:"wifi,omitempty" json:"wifi,omitempty"`
+	Parking      *bool `form:"parking,omitempty" json:"parking,omitempty"`
+	AirCondition *bool `form:"air_condition,omitempty" json:"air_condition,omitempty"`
+	Kitchen      *bool `form:"kitchen,omitempty" json:"kitchen,omitempty"`
+	PetFriendly  *bool `form:"pet_friendly,omitempty" json:"pet_friendly,omitempty"`
+
+	MaxGuests     *int `form:"max_guests,omitempty" json:"max_guests,omitempty" binding:"omitempty,gte=1"`
+	BedroomNumber *int `form:"bedroom_number,omitempty" json:"bedroom_number,omitempty" binding:"omitempty,gte=0"`
 }
 
 type ApartmentResponse struct {
@@ -96,8 +98,9 @@ type ApartmentResponse struct {
 	Country     string `json:"country"`
 	PostalCode  string `json:"postal_code"`
 
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
+	Latitude   float64  `json:"latitude"`
+	Longitude  float64  `json:"longitude"`
+	DistanceKm *float64 `json:"distance_km,omitempty"`
 
 	Wifi         bool `json:"wifi"`
 	Parking      bool `json:"parking"`
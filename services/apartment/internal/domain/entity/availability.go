@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+const (
+	EventBookingConfirmed = "booking.confirmed"
+	EventBookingCancelled = "booking.cancelled"
+)
+
+// BookedPeriod is a local projection of a booking made in the booking service.
+// Search uses it to skip apartments that are taken for the requested nights
+type BookedPeriod struct {
+	BookingID   string    `gorm:"primaryKey"`
+	ApartmentID string    `gorm:"not null;index:idx_booked_periods_apartment_dates,priority:1"`
+	CheckIn     time.Time `gorm:"type:date;not null;index:idx_booked_periods_apartment_dates,priority:2"`
+	CheckOut    time.Time `gorm:"type:date;not null"`
+	Active      bool      `gorm:"not null;default:true"`
+	OccurredAt  time.Time `gorm:"not null"` // time of the last applied event
+}
+
+type BookingEvent struct {
+	EventID    string          `json:"event_id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Booking    BookingSnapshot `json:"booking"`
+}
+
+type BookingSnapshot struct {
+	ID          string    `json:"id"`
+	ApartmentID string    `json:"apartment_id"`
+	CheckIn     time.Time `json:"check_in"`
+	CheckOut    time.Time `json:"check_out"`
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+const (
+	EventApartmentCreated = "apartment.created"
+	EventApartmentUpdated = "apartment.updated"
+	EventApartmentDeleted = "apartment.deleted"
+
+	ApartmentEventVersion = 1
+)
+
+type ApartmentEvent struct {
+	EventID    string            `json:"event_id"`
+	Type       string            `json:"type"`
+	Version    int               `json:"version"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Apartment  ApartmentSnapshot `json:"apartment"`
+}
+
+type ApartmentSnapshot struct {
+	ID            string  `json:"id"`
+	HostID        string  `json:"host_id"`
+	Title         string  `json:"title"`
+	PricePerNight float64 `json:"price_per_night"`
+
+	City      string  `json:"city"`
+	State     string  `json:"state"`
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+
+	Wifi         bool `json:"wifi"`
+	Parking      bool `json:"parking"`
+	AirCondition bool `json:"air_condition"`
+	Kitchen      bool `json:"kitchen"`
+	PetFriendly  bool `json:"pet_friendly"`
+
+	MaxGuests     int `json:"max_guests"`
+	BedroomNumber int `json:"bedroom_number"`
+}
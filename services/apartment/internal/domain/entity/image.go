@@ -4,11 +4,31 @@ type Image struct {
 	ID          string `gorm:"primaryKey"`
 	ApartmentID string `gorm:"index"`
 	Path        string `gorm:"size:255;not null"`
+	Caption     string `gorm:"size:500"`
+	Position    int    `gorm:"not null;default:0"`
 	IsCover     bool   `gorm:"default:false"`
 }
 
 type ImageResponse struct {
-	ID      string `json:"id"`
-	URL     string `json:"url"`
-	IsCover bool   `json:"is_cover"`
+	ID       string    `json:"id"`
+	URL      string    `json:"url"`
+	URLs     ImageURLs `json:"urls"`
+	Caption  string    `json:"caption"`
+	Position int       `json:"position"`
+	IsCover  bool      `json:"is_cover"`
+}
+
+// ImageURLs holds one URL per rendition produced by the image pipeline
+type ImageURLs struct {
+	Thumbnail string `json:"thumbnail"`
+	Medium    string `json:"medium"`
+	Full      string `json:"full"`
+}
+
+type ReorderImagesRequest struct {
+	ImageIDs []string `json:"image_ids" binding:"required,min=1"`
+}
+
+type UpdateImageRequest struct {
+	Caption string `json:"caption" binding:"max=500"`
 }
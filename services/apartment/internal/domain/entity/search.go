@@ -0,0 +1,103 @@
+package entity
+
+import "time"
+
+const (
+	SortNewest    = "newest"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortDistance  = "distance"
+)
+
+type SearchApartmentsRequest struct {
+	City        string   `form:"city"`
+	Country     string   `form:"country"`
+	MinPrice    *float64 `form:"min_price" binding:"omitempty,gte=0"`
+	MaxPrice    *float64 `form:"max_price" binding:"omitempty,gte=0"`
+	MinGuests   *int     `form:"min_guests" binding:"omitempty,gte=1"`
+	MinBedrooms *int     `form:"min_bedrooms" binding:"omitempty,gte=0"`
+
+	Wifi         *bool `form:"wifi"`
+	Parking      *bool `form:"parking"`
+	AirCondition *bool `form:"air_condition"`
+	Kitchen      *bool `form:"kitchen"`
+	PetFriendly  *bool `form:"pet_friendly"`
+
+	// radius search around a point
+	Latitude  *float64 `form:"lat" binding:"omitempty,gte=-90,lte=90"`
+	Longitude *float64 `form:"lng" binding:"omitempty,gte=-180,lte=180"`
+	RadiusKm  *float64 `form:"radius_km" binding:"omitempty,gt=0,lte=500"`
+
+	// map viewport, min_lng may be greater than max_lng when it crosses the antimeridian
+	MinLatitude  *float64 `form:"min_lat" binding:"omitempty,gte=-90,lte=90"`
+	MaxLatitude  *float64 `form:"max_lat" binding:"omitempty,gte=-90,lte=90"`
+	MinLongitude *float64 `form:"min_lng" binding:"omitempty,gte=-180,lte=180"`
+	MaxLongitude *float64 `form:"max_lng" binding:"omitempty,gte=-180,lte=180"`
+
+	// stay dates, DD-MM-YYYY. Apartments booked on any of these nights are skipped
+	CheckIn  *time.Time `form:"check_in" time_format:"02-01-2006"`
+	CheckOut *time.Time `form:"check_out" time_format:"02-01-2006"`
+
+	Sort   string `form:"sort"`
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,gte=1,lte=50"`
+}
+
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+type BoundingBox struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+// ApartmentFilter is the repository side of a search. After points at the last
+// apartment of the previous page, Limit is the page size
+type ApartmentFilter struct {
+	City        string
+	Country     string
+	MinPrice    *float64
+	MaxPrice    *float64
+	MinGuests   *int
+	MinBedrooms *int
+
+	Wifi         *bool
+	Parking      *bool
+	AirCondition *bool
+	Kitchen      *bool
+	PetFriendly  *bool
+
+	Near     *GeoPoint
+	RadiusKm float64
+	Box      *BoundingBox
+
+	// AvailableFrom and AvailableTo bound the half-open stay [check-in, check-out)
+	AvailableFrom *time.Time
+	AvailableTo   *time.Time
+
+	Sort  string
+	After *ApartmentCursor
+	Limit int
+}
+
+type ApartmentCursor struct {
+	Sort          string    `json:"sort"`
+	ID            string    `json:"id"`
+	PricePerNight float64   `json:"price,omitempty"`
+	CreatedAt     time.Time `json:"created_at,omitempty"`
+	DistanceKm    float64   `json:"distance_km,omitempty"`
+	// Latitude, Longitude and RadiusKm are the search point of a distance
+	// cursor. Distances from another point cannot continue the page
+	Latitude  float64 `json:"lat,omitempty"`
+	Longitude float64 `json:"lng,omitempty"`
+	RadiusKm  float64 `json:"radius_km,omitempty"`
+}
+
+type SearchApartmentsResponse struct {
+	Items      []ApartmentResponse `json:"items"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"airbnb-clone/apt/internal/adapters/repository"
+	"airbnb-clone/apt/internal/domain/entity"
+	"fmt"
+	"log/slog"
+)
+
+type AvailabilityService interface {
+	SyncBooking(event *entity.BookingEvent) error
+}
+
+type availabilityService struct {
+	repo repository.AvailabilityRepository
+	log  *slog.Logger
+}
+
+func NewAvailabilityService(repo repository.AvailabilityRepository, log *slog.Logger) AvailabilityService {
+	return &availabilityService{repo: repo, log: log}
+}
+
+// SyncBooking applies a booking event to the availability projection
+func (s *availabilityService) SyncBooking(event *entity.BookingEvent) error {
+	const fn = "domain.service.SyncBooking"
+	log := s.log.With(slog.String("fn", fn), slog.String("event_id", event.EventID))
+
+	booking := event.Booking
+	if booking.ID == "" || booking.ApartmentID == "" || event.OccurredAt.IsZero() || !booking.CheckOut.After(booking.CheckIn) {
+		return fmt.Errorf("%s: %w", fn, ErrInvalidEvent)
+	}
+
+	period := &entity.BookedPeriod{
+		BookingID:   booking.ID,
+		ApartmentID: booking.ApartmentID,
+		CheckIn:     booking.CheckIn,
+		CheckOut:    booking.CheckOut,
+		OccurredAt:  event.OccurredAt,
+	}
+
+	switch event.Type {
+	case entity.EventBookingConfirmed:
+		period.Active = true
+	case entity.EventBookingCancelled:
+		period.Active = false
+	default:
+		return fmt.Errorf("%s: %w: %s", fn, ErrInvalidEvent, event.Type)
+	}
+
+	if err := s.repo.UpsertBookedPeriod(period); err != nil {
+		log.Error("failed to upsert booked period", slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
@@ -4,7 +4,10 @@ import "errors"
 
 var (
 	ErrAptNotFound   = errors.New("apartment not found")
+	ErrImageNotFound = errors.New("image not found")
 	ErrInvalidInput  = errors.New("invalid input data")
 	ErrInvalidImage  = errors.New("invalid image file")
 	ErrImageTooLarge = errors.New("image size too large")
+	ErrInvalidEvent  = errors.New("invalid booking event")
+	ErrForbidden     = errors.New("only the host can modify the apartment")
 )
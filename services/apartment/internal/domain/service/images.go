@@ -0,0 +1,387 @@
+package service
+
+import (
+	"airbnb-clone/apt/internal/adapters/repository"
+	"airbnb-clone/apt/internal/domain/entity"
+	"airbnb-clone/shared/imaging"
+	"airbnb-clone/shared/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+func (s *apartmentService) AddImages(aptID string, userID string, imageFiles []*multipart.FileHeader, captions []string) ([]entity.ImageResponse, error) {
+	const fn = "domain.service.AddImages"
+	log := s.log.With(slog.String("fn", fn))
+
+	if len(imageFiles) == 0 {
+		return nil, fmt.Errorf("%s: %w", fn, ErrInvalidInput)
+	}
+
+	if _, err := s.getOwnedApartment(aptID, userID); err != nil {
+		return nil, err
+	}
+
+	images, err := s.saveImages(aptID, imageFiles, captions)
+	if err != nil {
+		log.Error("failed to save image", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var all []entity.Image
+	err = s.repo.WithinTransaction(func(repo repository.ApartmentRepository) error {
+		if err := appendImages(repo, aptID, images); err != nil {
+			return err
+		}
+		var err error
+		all, err = repo.GetApartmentImages(aptID)
+		return err
+	})
+	if err != nil {
+		log.Error("failed to add images", slog.String("error", err.Error()))
+		s.removeImageFiles(images)
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return toImageResponses(all), nil
+}
+
+// DeleteImage removes a single image. If it was the cover, the next image in
+// order becomes the cover. The file is removed only after the row is gone
+func (s *apartmentService) DeleteImage(aptID string, userID string, imageID string) error {
+	const fn = "domain.service.DeleteImage"
+	log := s.log.With(slog.String("fn", fn))
+
+	if _, err := s.getOwnedApartment(aptID, userID); err != nil {
+		return err
+	}
+
+	var deleted *entity.Image
+	err := s.repo.WithinTransaction(func(repo repository.ApartmentRepository) error {
+		var err error
+		deleted, err = repo.GetImage(aptID, imageID)
+		if err != nil {
+			return err
+		}
+		if err := repo.DeleteImage(imageID); err != nil {
+			return err
+		}
+
+		images, err := repo.GetApartmentImages(aptID)
+		if err != nil {
+			return err
+		}
+		ids := make([]string, 0, len(images))
+		for _, img := range images {
+			ids = append(ids, img.ID)
+		}
+		if err := writeImageOrder(repo, images, ids); err != nil {
+			return err
+		}
+		if deleted.IsCover && len(images) > 0 {
+			return repo.UpdateImageFields(images[0].ID, map[string]interface{}{"is_cover": true})
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrImageNotFound) {
+			return ErrImageNotFound
+		}
+		log.Error("failed to delete image", slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	s.removeImageFile(deleted.Path)
+	return nil
+}
+
+// ReorderImages sets positions to follow imageIDs, which must list every image
+// of the apartment exactly once
+func (s *apartmentService) ReorderImages(aptID string, userID string, imageIDs []string) ([]entity.ImageResponse, error) {
+	const fn = "domain.service.ReorderImages"
+	log := s.log.With(slog.String("fn", fn))
+
+	if _, err := s.getOwnedApartment(aptID, userID); err != nil {
+		return nil, err
+	}
+
+	var images []entity.Image
+	err := s.repo.WithinTransaction(func(repo repository.ApartmentRepository) error {
+		current, err := repo.GetApartmentImages(aptID)
+		if err != nil {
+			return err
+		}
+		if err := writeImageOrder(repo, current, imageIDs); err != nil {
+			return err
+		}
+		images, err = repo.GetApartmentImages(aptID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidInput) {
+			return nil, err
+		}
+		log.Error("failed to reorder images", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return toImageResponses(images), nil
+}
+
+func (s *apartmentService) SetCoverImage(aptID string, userID string, imageID string) ([]entity.ImageResponse, error) {
+	const fn = "domain.service.SetCoverImage"
+	log := s.log.With(slog.String("fn", fn))
+
+	if _, err := s.getOwnedApartment(aptID, userID); err != nil {
+		return nil, err
+	}
+
+	var images []entity.Image
+	err := s.repo.WithinTransaction(func(repo repository.ApartmentRepository) error {
+		var err error
+		images, err = repo.GetApartmentImages(aptID)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for i := range images {
+			isCover := images[i].ID == imageID
+			found = found || isCover
+			if images[i].IsCover == isCover {
+				continue
+			}
+			if err := repo.UpdateImageFields(images[i].ID, map[string]interface{}{"is_cover": isCover}); err != nil {
+				return err
+			}
+			images[i].IsCover = isCover
+		}
+		if !found {
+			return ErrImageNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrImageNotFound) {
+			return nil, err
+		}
+		log.Error("failed to set cover image", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return toImageResponses(images), nil
+}
+
+func (s *apartmentService) UpdateImageCaption(aptID string, userID string, imageID string, caption string) (*entity.ImageResponse, error) {
+	const fn = "domain.service.UpdateImageCaption"
+	log := s.log.With(slog.String("fn", fn))
+
+	if _, err := s.getOwnedApartment(aptID, userID); err != nil {
+		return nil, err
+	}
+
+	image, err := s.repo.GetImage(aptID, imageID)
+	if err != nil {
+		if errors.Is(err, repository.ErrImageNotFound) {
+			return nil, ErrImageNotFound
+		}
+		log.Error("failed to get image", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if err := s.repo.UpdateImageFields(imageID, map[string]interface{}{"caption": caption}); err != nil {
+		log.Error("failed to update image caption", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	image.Caption = caption
+
+	resp := toImageResponse(image)
+	return &resp, nil
+}
+
+// saveImage runs the upload through the image pipeline and stores every
+// rendition. It returns the key of the full-size rendition
+func (s *apartmentService) saveImage(file *multipart.FileHeader, apartmentID string) (string, error) {
+	if file.Size > 5*1024*1024 {
+		return "", ErrImageTooLarge
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	variants, err := imaging.Process(src)
+	if err != nil {
+		if errors.Is(err, imaging.ErrUnsupportedFormat) {
+			return "", ErrInvalidImage
+		}
+		if errors.Is(err, imaging.ErrImageTooLarge) {
+			return "", ErrImageTooLarge
+		}
+		return "", err
+	}
+
+	base := fmt.Sprintf("%s_%s", apartmentID, uuid.New().String())
+	var written []string
+	for _, variant := range variants {
+		key := imaging.FileName(base, variant.Name)
+		err := s.blobs.Put(context.Background(), key, bytes.NewReader(variant.Data), int64(len(variant.Data)), imaging.ContentType)
+		if err != nil {
+			for _, k := range written {
+				s.blobs.Delete(context.Background(), k)
+			}
+			return "", err
+		}
+		written = append(written, key)
+	}
+
+	return imaging.FileName(base, imaging.Full), nil
+}
+
+// saveImages stores the uploads. On failure the already stored blobs are
+// removed, so the caller has nothing to clean up
+func (s *apartmentService) saveImages(aptID string, imageFiles []*multipart.FileHeader, captions []string) ([]entity.Image, error) {
+	var images []entity.Image
+	for i, file := range imageFiles {
+		path, err := s.saveImage(file, aptID)
+		if err != nil {
+			s.removeImageFiles(images)
+			return nil, err
+		}
+
+		img := entity.Image{
+			ID:          uuid.New().String(),
+			ApartmentID: aptID,
+			Path:        path,
+		}
+		if i < len(captions) {
+			img.Caption = captions[i]
+		}
+		images = append(images, img)
+	}
+
+	return images, nil
+}
+
+// appendImages places images after the existing ones. The first of them becomes
+// the cover only when the apartment has no cover yet
+func appendImages(repo repository.ApartmentRepository, aptID string, images []entity.Image) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	existing, err := repo.GetApartmentImages(aptID)
+	if err != nil {
+		return err
+	}
+
+	hasCover := false
+	for _, img := range existing {
+		hasCover = hasCover || img.IsCover
+	}
+
+	for i := range images {
+		images[i].Position = len(existing) + i
+		images[i].IsCover = !hasCover && i == 0
+	}
+
+	return repo.AddImages(aptID, images)
+}
+
+// writeImageOrder stores positions following ids, which must be a permutation
+// of the ids of images
+func writeImageOrder(repo repository.ApartmentRepository, images []entity.Image, ids []string) error {
+	if len(ids) != len(images) {
+		return ErrInvalidInput
+	}
+
+	positions := make(map[string]int, len(images))
+	for _, img := range images {
+		positions[img.ID] = img.Position
+	}
+
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := positions[id]; !ok || seen[id] {
+			return ErrInvalidInput
+		}
+		seen[id] = true
+	}
+
+	for position, id := range ids {
+		if positions[id] == position {
+			continue
+		}
+		if err := repo.UpdateImageFields(id, map[string]interface{}{"position": position}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// OpenImage opens a stored rendition by the file name used in image URLs
+func (s *apartmentService) OpenImage(name string) (*storage.Blob, error) {
+	const fn = "domain.service.OpenImage"
+
+	blob, err := s.blobs.Get(context.Background(), name)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			return nil, ErrImageNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return blob, nil
+}
+
+func (s *apartmentService) removeImageFiles(images []entity.Image) {
+	for _, img := range images {
+		s.removeImageFile(img.Path)
+	}
+}
+
+// removeImageFile deletes every rendition of an image. Images uploaded before
+// the blob store existed are stored with their full path, hence filepath.Base
+func (s *apartmentService) removeImageFile(path string) {
+	for _, p := range imaging.VariantPaths(path) {
+		if err := s.blobs.Delete(context.Background(), filepath.Base(p)); err != nil {
+			s.log.Error("failed to delete image blob", slog.String("key", p), slog.String("error", err.Error()))
+		}
+	}
+}
+
+func toImageResponse(img *entity.Image) entity.ImageResponse {
+	return entity.ImageResponse{
+		ID:  img.ID,
+		URL: imageURL(img.Path, imaging.Full),
+		URLs: entity.ImageURLs{
+			Thumbnail: imageURL(img.Path, imaging.Thumbnail),
+			Medium:    imageURL(img.Path, imaging.Medium),
+			Full:      imageURL(img.Path, imaging.Full),
+		},
+		Caption:  img.Caption,
+		Position: img.Position,
+		IsCover:  img.IsCover,
+	}
+}
+
+func imageURL(path string, rendition string) string {
+	return "/uploads/" + filepath.Base(imaging.VariantPath(path, rendition))
+}
+
+func toImageResponses(images []entity.Image) []entity.ImageResponse {
+	resp := make([]entity.ImageResponse, 0, len(images))
+	for i := range images {
+		resp = append(resp, toImageResponse(&images[i]))
+	}
+	return resp
+}
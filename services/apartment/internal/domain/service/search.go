@@ -0,0 +1,207 @@
+package service
+
+import (
+	"airbnb-clone/apt/internal/domain/entity"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+func (s *apartmentService) SearchApartments(req *entity.SearchApartmentsRequest) (*entity.SearchApartmentsResponse, error) {
+	const fn = "domain.service.SearchApartments"
+	log := s.log.With(slog.String("fn", fn))
+
+	filter, err := toApartmentFilter(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	// one extra row tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+
+	apartments, err := s.repo.Search(filter)
+	if err != nil {
+		log.Error("failed to search apartments", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	resp := &entity.SearchApartmentsResponse{Items: make([]entity.ApartmentResponse, 0, limit)}
+	if len(apartments) > limit {
+		apartments = apartments[:limit]
+		resp.NextCursor = encodeCursor(filter, &apartments[limit-1])
+	}
+	for i := range apartments {
+		resp.Items = append(resp.Items, *toApartmentResponse(&apartments[i]))
+	}
+
+	return resp, nil
+}
+
+func toApartmentFilter(req *entity.SearchApartmentsRequest) (*entity.ApartmentFilter, error) {
+	if req.MinPrice != nil && req.MaxPrice != nil && *req.MinPrice > *req.MaxPrice {
+		return nil, ErrInvalidInput
+	}
+
+	filter := &entity.ApartmentFilter{
+		City:         req.City,
+		Country:      req.Country,
+		MinPrice:     req.MinPrice,
+		MaxPrice:     req.MaxPrice,
+		MinGuests:    req.MinGuests,
+		MinBedrooms:  req.MinBedrooms,
+		Wifi:         req.Wifi,
+		Parking:      req.Parking,
+		AirCondition: req.AirCondition,
+		Kitchen:      req.Kitchen,
+		PetFriendly:  req.PetFriendly,
+		Sort:         req.Sort,
+		Limit:        req.Limit,
+	}
+
+	if err := applyGeoFilter(filter, req); err != nil {
+		return nil, err
+	}
+
+	if (req.CheckIn == nil) != (req.CheckOut == nil) {
+		return nil, ErrInvalidInput
+	}
+	if req.CheckIn != nil {
+		if !req.CheckOut.After(*req.CheckIn) {
+			return nil, ErrInvalidInput
+		}
+		filter.AvailableFrom = req.CheckIn
+		filter.AvailableTo = req.CheckOut
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = entity.SortNewest
+		if filter.Near != nil {
+			filter.Sort = entity.SortDistance
+		}
+	case entity.SortNewest, entity.SortPriceAsc, entity.SortPriceDesc:
+	case entity.SortDistance:
+		if filter.Near == nil {
+			return nil, ErrInvalidInput
+		}
+	default:
+		return nil, ErrInvalidInput
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSearchLimit
+	}
+	filter.Limit = min(filter.Limit, maxSearchLimit)
+
+	if req.Cursor != "" {
+		cursor, err := decodeCursor(req.Cursor)
+		if err != nil || cursor.Sort != filter.Sort || cursor.ID == "" {
+			return nil, ErrInvalidInput
+		}
+		if filter.Sort == entity.SortDistance && (cursor.Latitude != filter.Near.Latitude ||
+			cursor.Longitude != filter.Near.Longitude || cursor.RadiusKm != filter.RadiusKm) {
+			return nil, ErrInvalidInput
+		}
+		filter.After = cursor
+	}
+
+	return filter, nil
+}
+
+// applyGeoFilter validates the radius and viewport parameters. Both lat and lng
+// are required for a radius search, and all four bounds for a viewport
+func applyGeoFilter(filter *entity.ApartmentFilter, req *entity.SearchApartmentsRequest) error {
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return ErrInvalidInput
+	}
+	if req.Latitude != nil {
+		if req.RadiusKm == nil {
+			return ErrInvalidInput
+		}
+		if !validLatitude(*req.Latitude) || !validLongitude(*req.Longitude) || *req.RadiusKm <= 0 {
+			return ErrInvalidInput
+		}
+		filter.Near = &entity.GeoPoint{Latitude: *req.Latitude, Longitude: *req.Longitude}
+		filter.RadiusKm = *req.RadiusKm
+	} else if req.RadiusKm != nil {
+		return ErrInvalidInput
+	}
+
+	bounds := []*float64{req.MinLatitude, req.MaxLatitude, req.MinLongitude, req.MaxLongitude}
+	given := 0
+	for _, b := range bounds {
+		if b != nil {
+			given++
+		}
+	}
+	switch given {
+	case 0:
+		return nil
+	case len(bounds):
+	default:
+		return ErrInvalidInput
+	}
+
+	box := &entity.BoundingBox{
+		MinLatitude:  *req.MinLatitude,
+		MaxLatitude:  *req.MaxLatitude,
+		MinLongitude: *req.MinLongitude,
+		MaxLongitude: *req.MaxLongitude,
+	}
+	if !validLatitude(box.MinLatitude) || !validLatitude(box.MaxLatitude) || box.MinLatitude > box.MaxLatitude ||
+		!validLongitude(box.MinLongitude) || !validLongitude(box.MaxLongitude) {
+		return ErrInvalidInput
+	}
+	filter.Box = box
+
+	return nil
+}
+
+func validLatitude(lat float64) bool {
+	return lat >= -90 && lat <= 90
+}
+
+func validLongitude(lng float64) bool {
+	return lng >= -180 && lng <= 180
+}
+
+func encodeCursor(filter *entity.ApartmentFilter, apt *entity.Apartment) string {
+	cursor := entity.ApartmentCursor{Sort: filter.Sort, ID: apt.ID}
+	switch filter.Sort {
+	case entity.SortNewest:
+		cursor.CreatedAt = apt.CreatedAt
+	case entity.SortDistance:
+		if apt.DistanceKm != nil {
+			cursor.DistanceKm = *apt.DistanceKm
+		}
+		cursor.Latitude = filter.Near.Latitude
+		cursor.Longitude = filter.Near.Longitude
+		cursor.RadiusKm = filter.RadiusKm
+	default:
+		cursor.PricePerNight = apt.PricePerNight
+	}
+
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (*entity.ApartmentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor entity.ApartmentCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
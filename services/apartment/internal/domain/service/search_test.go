@@ -0,0 +1,54 @@
+package service
+
+import (
+	"airbnb-clone/apt/internal/domain/entity"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+// searchRepository answers every search with the same apartments
+type searchRepository struct {
+	fakeRepository
+	results []entity.Apartment
+}
+
+func (r *searchRepository) Search(filter *entity.ApartmentFilter) ([]entity.Apartment, error) {
+	return r.results[:min(filter.Limit, len(r.results))], nil
+}
+
+func TestSearchDistanceCursorIsBoundToThePoint(t *testing.T) {
+	near, far := 1.5, 3.0
+	repo := &searchRepository{results: []entity.Apartment{{ID: "apt-1", DistanceKm: &near}, {ID: "apt-2", DistanceKm: &far}}}
+	svc := NewApartmentService(repo, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	lat, lng, radius := 38.7223, -9.1393, 10.0
+	first, err := svc.SearchApartments(&entity.SearchApartmentsRequest{Latitude: &lat, Longitude: &lng, RadiusKm: &radius, Limit: 1})
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if first.NextCursor == "" {
+		t.Fatal("first page has no cursor")
+	}
+
+	otherLat, otherRadius := 41.1579, 50.0
+	tests := []struct {
+		name     string
+		lat      *float64
+		radiusKm *float64
+		wantErr  error
+	}{
+		{name: "same point", lat: &lat, radiusKm: &radius},
+		{name: "other point", lat: &otherLat, radiusKm: &radius, wantErr: ErrInvalidInput},
+		{name: "other radius", lat: &lat, radiusKm: &otherRadius, wantErr: ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		_, err := svc.SearchApartments(&entity.SearchApartmentsRequest{Latitude: tt.lat, Longitude: &lng, RadiusKm: tt.radiusKm,
+			Limit: 1, Cursor: first.NextCursor})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -3,13 +3,13 @@ package service
 import (
 	"airbnb-clone/apt/internal/adapters/repository"
 	"airbnb-clone/apt/internal/domain/entity"
+	"airbnb-clone/shared/storage"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"mime/multipart"
-	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -17,22 +17,26 @@ import (
 type ApartmentService interface {
 	CreateApartment(req *entity.CreateApartmentRequest, hostID string, imageFiles []*multipart.FileHeader) (*entity.ApartmentResponse, error)
 	GetApartmentByID(id string) (*entity.ApartmentResponse, error)
-	DeleteApartment(id string) error
-	UpdateApartment(id string, updates map[string]interface{}, imageFiles []*multipart.FileHeader) (*entity.ApartmentResponse, error)
+	SearchApartments(req *entity.SearchApartmentsRequest) (*entity.SearchApartmentsResponse, error)
+	DeleteApartment(id string, userID string) error
+	RemoveApartment(id string) error
+	UpdateApartment(id string, userID string, req *entity.UpdateApartmentRequest, imageFiles []*multipart.FileHeader) (*entity.ApartmentResponse, error)
+	AddImages(aptID string, userID string, imageFiles []*multipart.FileHeader, captions []string) ([]entity.ImageResponse, error)
+	DeleteImage(aptID string, userID string, imageID string) error
+	ReorderImages(aptID string, userID string, imageIDs []string) ([]entity.ImageResponse, error)
+	SetCoverImage(aptID string, userID string, imageID string) ([]entity.ImageResponse, error)
+	UpdateImageCaption(aptID string, userID string, imageID string, caption string) (*entity.ImageResponse, error)
+	OpenImage(name string) (*storage.Blob, error)
 }
 
 type apartmentService struct {
-	repo      repository.ApartmentRepository
-	uploadDir string
-	log       *slog.Logger
+	repo  repository.ApartmentRepository
+	blobs storage.BlobStore
+	log   *slog.Logger
 }
 
-func NewApartmentService(repo repository.ApartmentRepository, uploadDir string, log *slog.Logger) ApartmentService {
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		log.Error("Failed to create upload directory: %v", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
-		os.Exit(1)
-	}
-	return &apartmentService{repo: repo, uploadDir: uploadDir, log: log}
+func NewApartmentService(repo repository.ApartmentRepository, blobs storage.BlobStore, log *slog.Logger) ApartmentService {
+	return &apartmentService{repo: repo, blobs: blobs, log: log}
 }
 
 func (s *apartmentService) CreateApartment(req *entity.CreateApartmentRequest, hostID string, imageFiles []*multipart.FileHeader) (*entity.ApartmentResponse, error) {
@@ -67,31 +71,26 @@ func (s *apartmentService) CreateApartment(req *entity.CreateApartmentRequest, h
 		BedroomNumber: req.BedroomNumber,
 	}
 
-	var images []entity.Image
-	for i, file := range imageFiles {
-		path, err := s.saveImage(file, apt.ID)
-		if err != nil {
-			log.Error("failed to save image", slog.String("error", err.Error()))
-			for _, img := range images {
-				os.Remove(img.Path)
-			}
-			return nil, err
-		}
-
-		images = append(images, entity.Image{
-			ID:          uuid.New().String(),
-			ApartmentID: apt.ID,
-			Path:        path,
-			IsCover:     i == 0, // первая картинка = cover
-		})
+	images, err := s.saveImages(apt.ID, imageFiles, nil)
+	if err != nil {
+		log.Error("failed to save image", slog.String("error", err.Error()))
+		return nil, err
+	}
+	for i := range images {
+		images[i].Position = i
+		images[i].IsCover = i == 0
 	}
 
 	apt.Images = images
 
-	if err := s.repo.CreateNewApartment(apt); err != nil {
-		for _, img := range images {
-			os.Remove(img.Path)
+	err = s.repo.WithinTransaction(func(repo repository.ApartmentRepository) error {
+		if err := repo.CreateNewApartment(apt); err != nil {
+			return err
 		}
+		return enqueueEvent(repo, entity.EventApartmentCreated, apt)
+	})
+	if err != nil {
+		s.removeImageFiles(images)
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
@@ -115,120 +114,263 @@ func (s *apartmentService) GetApartmentByID(id string) (*entity.ApartmentRespons
 	return toApartmentResponse(apt), nil
 }
 
-func (s *apartmentService) DeleteApartment(id string) error {
+func (s *apartmentService) DeleteApartment(id string, userID string) error {
 	const fn = "domain.service.DeleteApartment"
 	log := s.log.With(slog.String("fn", fn))
 
+	apt, err := s.getOwnedApartment(id, userID)
+	if err != nil {
+		log.Error("failed to get an apt by its id", slog.String("error", err.Error()))
+		return err
+	}
+
+	return s.deleteApartment(apt)
+}
+
+// RemoveApartment deletes a listing regardless of its host. It is meant for
+// moderators, the caller checks the permission
+func (s *apartmentService) RemoveApartment(id string) error {
+	const fn = "domain.service.RemoveApartment"
+	log := s.log.With(slog.String("fn", fn))
+
 	apt, err := s.repo.GetApartment(id)
 	if err != nil {
+		if errors.Is(err, repository.ErrAptNotFound) {
+			return ErrAptNotFound
+		}
 		log.Error("failed to get an apt by its id", slog.String("error", err.Error()))
 		return err
 	}
 
-	if err := s.repo.DeleteApartmentByID(id); err != nil {
+	if err := s.deleteApartment(apt); err != nil {
 		return err
 	}
 
-	for _, img := range apt.Images {
-		os.Remove(img.Path)
+	log.Info("apartment removed by moderator", slog.String("apartment_id", id), slog.String("host_id", apt.HostID))
+	return nil
+}
+
+func (s *apartmentService) deleteApartment(apt *entity.Apartment) error {
+	err := s.repo.WithinTransaction(func(repo repository.ApartmentRepository) error {
+		if err := repo.DeleteApartmentByID(apt.ID); err != nil {
+			return err
+		}
+		return enqueueEvent(repo, entity.EventApartmentDeleted, apt)
+	})
+	if err != nil {
+		return err
 	}
 
+	s.removeImageFiles(apt.Images)
+
 	return nil
 }
 
-func (s *apartmentService) UpdateApartment(id string, updates map[string]interface{}, imageFiles []*multipart.FileHeader) (*entity.ApartmentResponse, error) {
+func (s *apartmentService) UpdateApartment(id string, userID string, req *entity.UpdateApartmentRequest, imageFiles []*multipart.FileHeader) (*entity.ApartmentResponse, error) {
 	const fn = "domain.service.UpdateApartment"
 	log := s.log.With(slog.String("fn", fn))
 
-	var newImages []entity.Image
-	for i, file := range imageFiles {
-		path, err := s.saveImage(file, id)
-		if err != nil {
-			log.Error("failed to save image", slog.String("error", err.Error()))
-			for _, img := range newImages {
-				os.Remove(img.Path)
-			}
-			return nil, err
-		}
-		newImages = append(newImages, entity.Image{
-			ID:          uuid.New().String(),
-			ApartmentID: id,
-			Path:        path,
-			IsCover:     i == 0,
-		})
-	}
-
-	if len(updates) > 0 {
-		if err := s.repo.UpdateApartmentFields(id, updates); err != nil {
-			log.Error("failed to update apartment fields", slog.String("error", err.Error()))
-			return nil, err
-		}
+	updates, err := toApartmentUpdates(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if len(updates) == 0 && len(imageFiles) == 0 {
+		return nil, fmt.Errorf("%s: %w", fn, ErrInvalidInput)
 	}
 
-	if len(newImages) > 0 {
-		images, err := s.repo.GetApartmentImages(id)
-		if err != nil {
-			log.Error("failed to update apartment fields", slog.String("error", err.Error()))
-			return nil, err
+	if _, err := s.getOwnedApartment(id, userID); err != nil {
+		log.Error("failed to get an apt by its id", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	newImages, err := s.saveImages(id, imageFiles, nil)
+	if err != nil {
+		log.Error("failed to save image", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	var apt *entity.Apartment
+	err = s.repo.WithinTransaction(func(repo repository.ApartmentRepository) error {
+		if len(updates) > 0 {
+			if err := repo.UpdateApartmentFields(id, updates); err != nil {
+				log.Error("failed to update apartment fields", slog.String("error", err.Error()))
+				return err
+			}
 		}
-		for _, img := range images {
-			os.Remove(img.Path)
+
+		if err := appendImages(repo, id, newImages); err != nil {
+			log.Error("failed to add apartment images", slog.String("error", err.Error()))
+			return err
 		}
-		err = s.repo.DeleteApartmentImages(id)
+
+		var err error
+		apt, err = repo.GetApartment(id)
 		if err != nil {
-			log.Error("failed to update apartment fields", slog.String("error", err.Error()))
-			return nil, err
-		}
-		if err := s.repo.AddImages(id, newImages); err != nil {
-			for _, img := range newImages {
-				os.Remove(img.Path)
-			}
-			return nil, err
+			return err
 		}
-	}
-
-	apt, err := s.repo.GetApartment(id)
+		return enqueueEvent(repo, entity.EventApartmentUpdated, apt)
+	})
 	if err != nil {
+		s.removeImageFiles(newImages)
 		return nil, err
 	}
 
 	return toApartmentResponse(apt), nil
 }
 
-func (s *apartmentService) saveImage(file *multipart.FileHeader, apartmentID string) (string, error) {
-	if file.Size > 5*1024*1024 {
-		return "", ErrImageTooLarge
-	}
+// toApartmentUpdates validates the request and maps the provided fields to
+// their columns. Only columns listed here can ever be changed by a host
+func toApartmentUpdates(req *entity.UpdateApartmentRequest) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
 
-	allowedTypes := map[string]bool{
-		"image/jpeg": true,
-		"image/png":  true,
+	if req.Title != nil {
+		if *req.Title == "" {
+			return nil, ErrInvalidInput
+		}
+		updates["title"] = *req.Title
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.PricePerNight != nil {
+		if *req.PricePerNight <= 0 {
+			return nil, ErrInvalidInput
+		}
+		updates["price_per_night"] = *req.PricePerNight
+	}
+	if req.HouseNumber != nil {
+		if *req.HouseNumber <= 0 {
+			return nil, ErrInvalidInput
+		}
+		updates["house_number"] = *req.HouseNumber
+	}
+	if req.Street != nil {
+		if *req.Street == "" {
+			return nil, ErrInvalidInput
+		}
+		updates["street"] = *req.Street
+	}
+	if req.City != nil {
+		if *req.City == "" {
+			return nil, ErrInvalidInput
+		}
+		updates["city"] = *req.City
+	}
+	if req.State != nil {
+		updates["state"] = *req.State
 	}
-	if !allowedTypes[file.Header.Get("Content-Type")] {
-		return "", ErrInvalidImage
+	if req.Country != nil {
+		if *req.Country == "" {
+			return nil, ErrInvalidInput
+		}
+		updates["country"] = *req.Country
+	}
+	if req.PostalCode != nil {
+		updates["postal_code"] = *req.PostalCode
+	}
+	if req.Latitude != nil {
+		if !validLatitude(*req.Latitude) {
+			return nil, ErrInvalidInput
+		}
+		updates["latitude"] = *req.Latitude
+	}
+	if req.Longitude != nil {
+		if !validLongitude(*req.Longitude) {
+			return nil, ErrInvalidInput
+		}
+		updates["longitude"] = *req.Longitude
+	}
+	if req.Wifi != nil {
+		updates["wifi"] = *req.Wifi
+	}
+	if req.Parking != nil {
+		updates["parking"] = *req.Parking
+	}
+	if req.AirCondition != nil {
+		updates["air_condition"] = *req.AirCondition
+	}
+	if req.Kitchen != nil {
+		updates["kitchen"] = *req.Kitchen
+	}
+	if req.PetFriendly != nil {
+		updates["pet_friendly"] = *req.PetFriendly
+	}
+	if req.MaxGuests != nil {
+		if *req.MaxGuests < 1 {
+			return nil, ErrInvalidInput
+		}
+		updates["max_guests"] = *req.MaxGuests
+	}
+	if req.BedroomNumber != nil {
+		if *req.BedroomNumber < 0 {
+			return nil, ErrInvalidInput
+		}
+		updates["bedroom_number"] = *req.BedroomNumber
 	}
 
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("%s_%s%s", apartmentID, uuid.New().String(), ext)
-	filePath := filepath.Join(s.uploadDir, filename)
+	return updates, nil
+}
 
-	src, err := file.Open()
+// getOwnedApartment returns the apartment only if userID is its host
+func (s *apartmentService) getOwnedApartment(id string, userID string) (*entity.Apartment, error) {
+	apt, err := s.repo.GetApartment(id)
 	if err != nil {
-		return "", err
+		if errors.Is(err, repository.ErrAptNotFound) {
+			return nil, ErrAptNotFound
+		}
+		return nil, err
 	}
-	defer src.Close()
 
-	dst, err := os.Create(filePath)
-	if err != nil {
-		return "", err
+	if userID == "" || apt.HostID != userID {
+		return nil, ErrForbidden
 	}
-	defer dst.Close()
 
-	if _, err = io.Copy(dst, src); err != nil {
-		return "", err
+	return apt, nil
+}
+
+// enqueueEvent stores the event in the outbox within the caller's transaction.
+// The outbox relay publishes it once the transaction is committed
+func enqueueEvent(repo repository.ApartmentRepository, eventType string, apt *entity.Apartment) error {
+	event := newApartmentEvent(eventType, apt)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
 	}
 
-	return filePath, nil
+	return repo.AddOutboxMessage(&entity.OutboxMessage{
+		ID:            event.EventID,
+		AggregateID:   apt.ID,
+		EventType:     eventType,
+		Payload:       payload,
+		NextAttemptAt: event.OccurredAt,
+	})
+}
+
+func newApartmentEvent(eventType string, apt *entity.Apartment) *entity.ApartmentEvent {
+	return &entity.ApartmentEvent{
+		EventID:    uuid.New().String(),
+		Type:       eventType,
+		Version:    entity.ApartmentEventVersion,
+		OccurredAt: time.Now().UTC(),
+		Apartment: entity.ApartmentSnapshot{
+			ID:            apt.ID,
+			HostID:        apt.HostID,
+			Title:         apt.Title,
+			PricePerNight: apt.PricePerNight,
+			City:          apt.City,
+			State:         apt.State,
+			Country:       apt.Country,
+			Latitude:      apt.Latitude,
+			Longitude:     apt.Longitude,
+			Wifi:          apt.Wifi,
+			Parking:       apt.Parking,
+			AirCondition:  apt.AirCondition,
+			Kitchen:       apt.Kitchen,
+			PetFriendly:   apt.PetFriendly,
+			MaxGuests:     apt.MaxGuests,
+			BedroomNumber: apt.BedroomNumber,
+		},
+	}
 }
 
 func toApartmentResponse(apt *entity.Apartment) *entity.ApartmentResponse {
@@ -246,6 +388,7 @@ func toApartmentResponse(apt *entity.Apartment) *entity.ApartmentResponse {
 		PostalCode:    apt.PostalCode,
 		Latitude:      apt.Latitude,
 		Longitude:     apt.Longitude,
+		DistanceKm:    apt.DistanceKm,
 		Wifi:          apt.Wifi,
 		Parking:       apt.Parking,
 		AirCondition:  apt.AirCondition,
@@ -258,11 +401,7 @@ func toApartmentResponse(apt *entity.Apartment) *entity.ApartmentResponse {
 	}
 
 	for _, img := range apt.Images {
-		resp.Images = append(resp.Images, entity.ImageResponse{
-			ID:      img.ID,
-			URL:     "/uploads/" + filepath.Base(img.Path),
-			IsCover: img.IsCover,
-		})
+		resp.Images = append(resp.Images, toImageResponse(&img))
 	}
 
 	return resp
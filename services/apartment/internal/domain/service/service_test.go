@@ -0,0 +1,144 @@
+package service
+
+import (
+	"airbnb-clone/apt/internal/adapters/publisher"
+	"airbnb-clone/apt/internal/adapters/repository"
+	"airbnb-clone/apt/internal/domain/entity"
+	"airbnb-clone/shared/outbox"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// fakeRepository keeps apartments and outbox messages in memory. Only the
+// methods used by create, update and delete are implemented
+type fakeRepository struct {
+	repository.ApartmentRepository
+	apartments map[string]*entity.Apartment
+	outbox     []entity.OutboxMessage
+}
+
+func (r *fakeRepository) CreateNewApartment(apt *entity.Apartment) error {
+	copied := *apt
+	r.apartments[apt.ID] = &copied
+	return nil
+}
+
+func (r *fakeRepository) GetApartment(id string) (*entity.Apartment, error) {
+	apt, ok := r.apartments[id]
+	if !ok {
+		return nil, repository.ErrAptNotFound
+	}
+	copied := *apt
+	return &copied, nil
+}
+
+func (r *fakeRepository) UpdateApartmentFields(id string, updates map[string]interface{}) error {
+	if title, ok := updates["title"].(string); ok {
+		r.apartments[id].Title = title
+	}
+	return nil
+}
+
+func (r *fakeRepository) DeleteApartmentByID(id string) error {
+	delete(r.apartments, id)
+	return nil
+}
+
+func (r *fakeRepository) AddOutboxMessage(message *entity.OutboxMessage) error {
+	r.outbox = append(r.outbox, *message)
+	return nil
+}
+
+func (r *fakeRepository) WithinTransaction(fn func(repo repository.ApartmentRepository) error) error {
+	return fn(r)
+}
+
+// fakeOutboxStore hands the messages of a fakeRepository to the relay
+type fakeOutboxStore struct {
+	repo *fakeRepository
+}
+
+func (s *fakeOutboxStore) FetchPendingOutbox(limit int, _ time.Time) ([]outbox.Message, error) {
+	var messages []outbox.Message
+	for _, msg := range s.repo.outbox {
+		if msg.PublishedAt == nil && len(messages) < limit {
+			messages = append(messages, outbox.Message{ID: msg.ID, AggregateID: msg.AggregateID, Payload: msg.Payload})
+		}
+	}
+	return messages, nil
+}
+
+func (s *fakeOutboxStore) MarkOutboxPublished(id string) error {
+	now := time.Now()
+	for i := range s.repo.outbox {
+		if s.repo.outbox[i].ID == id {
+			s.repo.outbox[i].PublishedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *fakeOutboxStore) MarkOutboxFailed(string, string, time.Time) error { return nil }
+
+func (s *fakeOutboxStore) MarkOutboxDead(string, string) error { return nil }
+
+func (s *fakeOutboxStore) GetOutboxStats() (*outbox.Stats, error) { return &outbox.Stats{}, nil }
+
+func TestApartmentChangesPublishEvents(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := &fakeRepository{apartments: map[string]*entity.Apartment{}}
+	svc := NewApartmentService(repo, nil, log)
+
+	created, err := svc.CreateApartment(&entity.CreateApartmentRequest{Title: "Loft", PricePerNight: 100, City: "Lisbon"}, "host-1", nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	title := "Sunny loft"
+	if _, err := svc.UpdateApartment(created.ID, "host-1", &entity.UpdateApartmentRequest{Title: &title}, nil); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := svc.DeleteApartment(created.ID, "host-1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	// Run drains the outbox once before it looks at the context
+	pub := publisher.NewInMemoryPublisher()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	outbox.NewRelay[entity.ApartmentEvent](&fakeOutboxStore{repo: repo}, pub, outbox.Config{
+		PollInterval:   time.Second,
+		BatchSize:      10,
+		PublishTimeout: time.Second,
+		MaxBackoff:     time.Second,
+	}, log).Run(ctx)
+
+	want := []struct {
+		eventType string
+		title     string
+	}{
+		{entity.EventApartmentCreated, "Loft"},
+		{entity.EventApartmentUpdated, "Sunny loft"},
+		{entity.EventApartmentDeleted, "Sunny loft"},
+	}
+	events := pub.Events()
+	if len(events) != len(want) {
+		t.Fatalf("published %d events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.Type != want[i].eventType || event.Apartment.ID != created.ID || event.Apartment.Title != want[i].title {
+			t.Errorf("event %d = %s %s %q, want %s %s %q", i, event.Type, event.Apartment.ID, event.Apartment.Title,
+				want[i].eventType, created.ID, want[i].title)
+		}
+		if event.Version != entity.ApartmentEventVersion || event.Apartment.HostID != "host-1" {
+			t.Errorf("event %d has version %d and host %q", i, event.Version, event.Apartment.HostID)
+		}
+	}
+	for _, msg := range repo.outbox {
+		if msg.PublishedAt == nil {
+			t.Errorf("outbox message %s (%s) was not marked as published", msg.ID, msg.EventType)
+		}
+	}
+}
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"airbnb-clone/auth/internal/adapters/grpc_server"
 	"airbnb-clone/auth/internal/adapters/http_server"
+	"airbnb-clone/auth/internal/adapters/keys"
+	"airbnb-clone/auth/internal/adapters/lockout"
+	"airbnb-clone/auth/internal/adapters/mailer"
+	"airbnb-clone/auth/internal/adapters/oidc"
 	"airbnb-clone/auth/internal/adapters/repository"
 	"airbnb-clone/auth/internal/config"
 	"airbnb-clone/auth/internal/domain/service"
+	"context"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,8 +36,56 @@ func main() {
 		os.Exit(1)
 	}
 
-	authService := service.NewAuthService(authRepo, log)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	keyManager := keys.NewManager(authRepo, cfg.JWT, log)
+	if err := keyManager.Init(); err != nil {
+		log.Error("failed to load signing keys", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	go keyManager.Run(ctx)
+
+	mailSender, err := mailer.New(cfg.Mail, log)
+	if err != nil {
+		log.Error("failed to setup mailer", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	attemptStore, err := lockout.NewStore(cfg.Lockout, authRepo)
+	if err != nil {
+		log.Error("failed to setup lockout store", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	limiter := lockout.NewLimiter(attemptStore, cfg.Lockout)
+
+	oidcProviders := oidc.NewRegistry(cfg.OIDC)
+
+	authService := service.NewAuthService(authRepo, keyManager, mailSender, limiter, oidcProviders, cfg, log)
+	if err := authService.BootstrapAdmin(); err != nil {
+		log.Error("failed to grant bootstrap admin role", slog.String("error", err.Error()))
+	}
+
+	if cfg.GRPC.ListenAddress != "" {
+		grpcServer, err := grpc_server.NewServer(cfg.GRPC, grpc_server.NewAuthServer(log, authService), log)
+		if err != nil {
+			log.Error("failed to setup grpc server", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		go func() {
+			if err := grpcServer.Run(ctx); err != nil {
+				log.Error("grpc server stopped", slog.String("error", err.Error()))
+			}
+		}()
+	}
+
 	r := setUpHttpServer(log, authService)
+	// the client IP feeds the login lockout, it must not be taken from a header
+	// any client can set
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Error("invalid trusted proxies", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 	if err := r.Run(cfg.Address); err != nil {
 		log.Error("Failed to start server:", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
 	}
@@ -38,7 +94,7 @@ func main() {
 func setUpHttpServer(log *slog.Logger, authService service.AuthService) *gin.Engine {
 	r := gin.Default()
 	authController := http_server.NewAuthController(log, authService)
-	http_server.SetupAuthRoutes(r, authController)
+	http_server.SetupAuthRoutes(r, authController, authService)
 	return r
 }
 
@@ -0,0 +1,92 @@
+package grpc_server
+
+import (
+	"airbnb-clone/auth/internal/domain/service"
+	"airbnb-clone/shared/authv1"
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type authServer struct {
+	authv1.UnimplementedAuthServiceServer
+	authService service.AuthService
+	log         *slog.Logger
+}
+
+func NewAuthServer(logger *slog.Logger, authService service.AuthService) authv1.AuthServiceServer {
+	return &authServer{authService: authService, log: logger}
+}
+
+func (s *authServer) IntrospectToken(ctx context.Context, request *authv1.IntrospectTokenRequest) (*authv1.IntrospectTokenResponse, error) {
+	if request.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	introspection, err := s.authService.IntrospectToken(request.GetToken())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to introspect token")
+	}
+	if !introspection.Active {
+		return &authv1.IntrospectTokenResponse{Active: false}, nil
+	}
+
+	return &authv1.IntrospectTokenResponse{
+		Active:        true,
+		UserId:        introspection.UserID,
+		SessionId:     introspection.SessionID,
+		EmailVerified: introspection.EmailVerified,
+		Roles:         introspection.Roles,
+		Permissions:   introspection.Permissions,
+		AuthMethods:   introspection.AuthMethods,
+		IssuedAt:      timestamppb.New(introspection.IssuedAt),
+		ExpiresAt:     timestamppb.New(introspection.ExpiresAt),
+	}, nil
+}
+
+func (s *authServer) GetUser(ctx context.Context, request *authv1.GetUserRequest) (*authv1.GetUserResponse, error) {
+	user, err := s.authService.GetUser(request.GetUserId())
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "failed to get user")
+	}
+
+	return &authv1.GetUserResponse{User: toProtoUser(user)}, nil
+}
+
+func (s *authServer) BatchGetUsers(ctx context.Context, request *authv1.BatchGetUsersRequest) (*authv1.BatchGetUsersResponse, error) {
+	users, missingIDs, err := s.authService.BatchGetUsers(request.GetUserIds())
+	if err != nil {
+		if errors.Is(err, service.ErrTooManyUsers) {
+			return nil, status.Errorf(codes.InvalidArgument, "at most %d users can be requested at once", service.MaxBatchUsers)
+		}
+		return nil, status.Error(codes.Internal, "failed to get users")
+	}
+
+	response := &authv1.BatchGetUsersResponse{Users: make([]*authv1.User, 0, len(users)), MissingIds: missingIDs}
+	for i := range users {
+		response.Users = append(response.Users, toProtoUser(&users[i]))
+	}
+	return response, nil
+}
+
+func toProtoUser(user *service.UserInfo) *authv1.User {
+	protoUser := &authv1.User{
+		Id:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		MfaEnabled:    user.MFAEnabled,
+		Roles:         user.Roles,
+	}
+	if user.LockedUntil != nil {
+		protoUser.Locked = true
+		protoUser.LockedUntil = timestamppb.New(*user.LockedUntil)
+	}
+	return protoUser
+}
@@ -0,0 +1,119 @@
+package grpc_server
+
+import (
+	"airbnb-clone/auth/internal/config"
+	"airbnb-clone/shared/authv1"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Server serves the internal API of the auth service
+type Server struct {
+	server  *grpc.Server
+	address string
+	log     *slog.Logger
+}
+
+func NewServer(cfg config.GRPC, api authv1.AuthServiceServer, log *slog.Logger) (*Server, error) {
+	const fn = "adapters.grpc_server.NewServer"
+
+	creds, err := serverCredentials(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	// the API hands out user data and checks tokens for any caller, so
+	// without client certificates only local callers may reach it
+	if !isMutualTLS(cfg) {
+		if err := requireLoopback(cfg.ListenAddress); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	server := grpc.NewServer(grpc.Creds(creds))
+	authv1.RegisterAuthServiceServer(server, api)
+
+	return &Server{server: server, address: cfg.ListenAddress, log: log}, nil
+}
+
+// Run serves until ctx is done, then lets running calls finish
+func (s *Server) Run(ctx context.Context) error {
+	const fn = "adapters.grpc_server.Run"
+
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.server.GracefulStop()
+	}()
+
+	s.log.Info("grpc server started", slog.String("address", s.address))
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+// serverCredentials is plaintext without a certificate, TLS with one, and
+// mutual TLS once a client CA is configured as well
+func serverCredentials(cfg config.GRPC) (credentials.TransportCredentials, error) {
+	if cfg.CertFile == "" && cfg.KeyFile == "" {
+		if cfg.ClientCAFile != "" {
+			return nil, errors.New("client ca is set without a server certificate")
+		}
+		return insecure.NewCredentials(), nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+
+	if cfg.ClientCAFile != "" {
+		caPEM, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates in %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+func isMutualTLS(cfg config.GRPC) bool {
+	return cfg.CertFile != "" && cfg.KeyFile != "" && cfg.ClientCAFile != ""
+}
+
+// requireLoopback fails unless the address only listens on the loopback
+// interface
+func requireLoopback(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("%q is not a loopback address, other addresses require cert_file, key_file and client_ca_file", address)
+}
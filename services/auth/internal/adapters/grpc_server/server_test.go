@@ -0,0 +1,33 @@
+package grpc_server
+
+import (
+	"airbnb-clone/auth/internal/config"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestNewServerRequiresMutualTLSOffLoopback(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "localhost:9000"},
+		{address: "127.0.0.1:9000"},
+		{address: "[::1]:9000"},
+		{address: ":9000", wantErr: true},
+		{address: "0.0.0.0:9000", wantErr: true},
+		{address: "10.0.0.5:9000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			_, err := NewServer(config.GRPC{ListenAddress: tt.address}, NewAuthServer(log, nil), log)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
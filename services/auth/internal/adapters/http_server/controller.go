@@ -1,6 +1,7 @@
 package http_server
 
 import (
+	"airbnb-clone/auth/internal/domain/entity"
 	"airbnb-clone/auth/internal/domain/service"
 	"errors"
 	"log/slog"
@@ -13,6 +14,25 @@ type AuthController interface {
 	Register(ctx *gin.Context)
 	Login(ctx *gin.Context)
 	Refresh(ctx *gin.Context)
+	Logout(ctx *gin.Context)
+	LogoutAll(ctx *gin.Context)
+	GetSessions(ctx *gin.Context)
+	RevokeSession(ctx *gin.Context)
+	VerifyEmail(ctx *gin.Context)
+	ResendVerificationEmail(ctx *gin.Context)
+	ForgotPassword(ctx *gin.Context)
+	ResetPassword(ctx *gin.Context)
+	ChangePassword(ctx *gin.Context)
+	EnrollTOTP(ctx *gin.Context)
+	ConfirmTOTP(ctx *gin.Context)
+	DisableTOTP(ctx *gin.Context)
+	LoginMFA(ctx *gin.Context)
+	OIDCLogin(ctx *gin.Context)
+	OIDCCallback(ctx *gin.Context)
+	GetUserRoles(ctx *gin.Context)
+	GrantRole(ctx *gin.Context)
+	RevokeRole(ctx *gin.Context)
+	JWKS(ctx *gin.Context)
 }
 
 type authController struct {
@@ -42,7 +62,7 @@ func (c *authController) Register(ctx *gin.Context) {
 		return
 	}
 
-	jwtTokenPair, err := c.authService.RegisterNewUser(request.Email, request.Password)
+	jwtTokenPair, err := c.authService.RegisterNewUser(request.Email, request.Password, clientInfo(ctx))
 	if err != nil {
 		if errors.Is(err, service.ErrEmailExist) {
 			ctx.JSON(http.StatusConflict, gin.H{"error": "User with provided email already exists"})
@@ -71,15 +91,25 @@ func (c *authController) Login(ctx *gin.Context) {
 		return
 	}
 
-	jwtTokenPair, err := c.authService.LoginExistingUser(request.Email, request.Password)
+	jwtTokenPair, err := c.authService.LoginExistingUser(request.Email, request.Password, clientInfo(ctx))
 	if err != nil {
-		if errors.Is(err, service.ErrEmailNotFound) {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User with provided email was not found"})
+		var mfaErr *service.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			ctx.JSON(http.StatusOK, mfaChallengeResponse{
+				MFARequired: true,
+				MFAToken:    mfaErr.ChallengeToken,
+				ExpiresAt:   mfaErr.ExpiresAt,
+			})
 			return
 		}
 
-		if errors.Is(err, service.ErrInvalidPassword) {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+			return
+		}
+		var rateErr *service.RateLimitError
+		if errors.As(err, &rateErr) {
+			tooManyRequests(ctx, rateErr)
 			return
 		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -105,7 +135,7 @@ func (c *authController) Refresh(ctx *gin.Context) {
 		return
 	}
 
-	accessToken, err := c.authService.ValidateRefreshToken(request.RefreshToken)
+	jwtTokenPair, err := c.authService.RefreshTokens(request.RefreshToken, clientInfo(ctx))
 	if err != nil {
 		if errors.Is(err, service.ErrRefreshTokenNotFound) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token not found"})
@@ -115,9 +145,26 @@ func (c *authController) Refresh(ctx *gin.Context) {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token expired"})
 			return
 		}
+		if errors.Is(err, service.ErrRefreshTokenRevoked) || errors.Is(err, service.ErrRefreshTokenReused) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token revoked, please log in again"})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"access_token": accessToken})
+	ctx.JSON(http.StatusOK, JWTTokenResponse{
+		AccessToken:  jwtTokenPair.AccessToken,
+		RefreshToken: jwtTokenPair.RefreshToken,
+	})
+}
+
+func (c *authController) JWKS(ctx *gin.Context) {
+	// short enough for verifiers to pick up a new key before it signs
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, c.authService.JWKS())
+}
+
+func clientInfo(ctx *gin.Context) entity.ClientInfo {
+	return entity.ClientInfo{UserAgent: ctx.Request.UserAgent(), IP: ctx.ClientIP()}
 }
@@ -0,0 +1,180 @@
+package http_server
+
+import (
+	"airbnb-clone/auth/internal/adapters/http_server/middleware"
+	"airbnb-clone/auth/internal/domain/service"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type mfaChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type totpEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+func (c *authController) EnrollTOTP(ctx *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	enrollment, err := c.authService.EnrollTOTP(userID)
+	if err != nil {
+		if errors.Is(err, service.ErrMFAAlreadyEnabled) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+		if errors.Is(err, service.ErrUserNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, totpEnrollmentResponse{Secret: enrollment.Secret, OTPAuthURI: enrollment.URI})
+}
+
+func (c *authController) ConfirmTOTP(ctx *gin.Context) {
+	const fn = "adapters.controller.ConfirmTOTP"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var request mfaCodeRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		log.Error("failed to parse json body", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recoveryCodes, err := c.authService.ConfirmTOTP(userID, request.Code, clientInfo(ctx))
+	if err != nil {
+		var rateErr *service.RateLimitError
+		if errors.As(err, &rateErr) {
+			tooManyRequests(ctx, rateErr)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidMFACode) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+			return
+		}
+		if errors.Is(err, service.ErrMFANotPending) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Two-factor enrollment was not started"})
+			return
+		}
+		if errors.Is(err, service.ErrMFAAlreadyEnabled) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+		if errors.Is(err, service.ErrUserNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+func (c *authController) DisableTOTP(ctx *gin.Context) {
+	const fn = "adapters.controller.DisableTOTP"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var request mfaCodeRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		log.Error("failed to parse json body", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.authService.DisableTOTP(userID, request.Code, clientInfo(ctx)); err != nil {
+		var rateErr *service.RateLimitError
+		if errors.As(err, &rateErr) {
+			tooManyRequests(ctx, rateErr)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidMFACode) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+			return
+		}
+		if errors.Is(err, service.ErrMFANotEnabled) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is not enabled"})
+			return
+		}
+		if errors.Is(err, service.ErrUserNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
+func (c *authController) LoginMFA(ctx *gin.Context) {
+	const fn = "adapters.controller.LoginMFA"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	var request loginMFARequest
+	if err := ctx.BindJSON(&request); err != nil {
+		log.Error("failed to parse json body", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	jwtTokenPair, err := c.authService.CompleteMFALogin(request.MFAToken, request.Code, clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMFAChallenge) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Login attempt expired, please log in again"})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidMFACode) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+			return
+		}
+		var rateErr *service.RateLimitError
+		if errors.As(err, &rateErr) {
+			tooManyRequests(ctx, rateErr)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, JWTTokenResponse{
+		AccessToken:  jwtTokenPair.AccessToken,
+		RefreshToken: jwtTokenPair.RefreshToken,
+	})
+}
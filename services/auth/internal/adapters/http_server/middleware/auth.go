@@ -3,16 +3,19 @@ package middleware
 import (
 	"errors"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 )
 
 const UserIDKey = "userID"
 
-func AuthMiddleware() gin.HandlerFunc {
+// TokenValidator checks an access token and returns the user it was issued to
+type TokenValidator interface {
+	ValidateAccessToken(token string) (string, error)
+}
+
+func AuthMiddleware(validator TokenValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -28,9 +31,7 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-
-		userID, err := parseJWTToken(tokenString)
+		userID, err := validator.ValidateAccessToken(parts[1])
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
@@ -42,22 +43,6 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-func parseJWTToken(tokenString string) (string, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
-	})
-	if err != nil {
-		return "", err
-	}
-
-	claims := token.Claims.(jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	return userID, nil
-}
-
 func GetUserIDFromContext(c *gin.Context) (string, error) {
 	userID, exists := c.Get(UserIDKey)
 	if !exists {
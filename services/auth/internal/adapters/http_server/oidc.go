@@ -0,0 +1,84 @@
+package http_server
+
+import (
+	"airbnb-clone/auth/internal/domain/service"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCLogin redirects the browser to the identity provider
+func (c *authController) OIDCLogin(ctx *gin.Context) {
+	authURL, err := c.authService.StartOIDCLogin(ctx.Param("provider"))
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownProvider) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+			return
+		}
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback is where the identity provider sends the browser back with an
+// authorization code
+func (c *authController) OIDCCallback(ctx *gin.Context) {
+	const fn = "adapters.controller.OIDCCallback"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		log.Info("identity provider denied the login", slog.String("error", providerErr))
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Login was denied by the identity provider"})
+		return
+	}
+
+	code, state := ctx.Query("code"), ctx.Query("state")
+	if code == "" || state == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Code and state are required"})
+		return
+	}
+
+	jwtTokenPair, err := c.authService.CompleteOIDCLogin(ctx.Param("provider"), code, state, clientInfo(ctx))
+	if err != nil {
+		var mfaErr *service.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			ctx.JSON(http.StatusOK, mfaChallengeResponse{
+				MFARequired: true,
+				MFAToken:    mfaErr.ChallengeToken,
+				ExpiresAt:   mfaErr.ExpiresAt,
+			})
+			return
+		}
+
+		switch {
+		case errors.Is(err, service.ErrUnknownProvider):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		case errors.Is(err, service.ErrInvalidOIDCState):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Login is invalid or expired, please start again"})
+		case errors.Is(err, service.ErrOIDCFailed):
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Identity provider login failed"})
+		case errors.Is(err, service.ErrOIDCEmailNotVerified):
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Identity provider did not verify the email"})
+		case errors.Is(err, service.ErrAccountNotVerified):
+			ctx.JSON(http.StatusConflict, gin.H{"error": "An account with this email exists but its email is not verified, verify it first"})
+		case errors.Is(err, service.ErrIdentityConflict):
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Account is already linked, please try again"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, JWTTokenResponse{
+		AccessToken:  jwtTokenPair.AccessToken,
+		RefreshToken: jwtTokenPair.RefreshToken,
+	})
+}
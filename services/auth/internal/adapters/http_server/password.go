@@ -0,0 +1,101 @@
+package http_server
+
+import (
+	"airbnb-clone/auth/internal/adapters/http_server/middleware"
+	"airbnb-clone/auth/internal/domain/service"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (c *authController) ForgotPassword(ctx *gin.Context) {
+	const fn = "adapters.controller.ForgotPassword"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	var request forgotPasswordRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		log.Error("failed to parse json body", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.authService.ForgotPassword(request.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process the request"})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"status": "If an account uses this email, a reset link has been sent to it"})
+}
+
+func (c *authController) ResetPassword(ctx *gin.Context) {
+	const fn = "adapters.controller.ResetPassword"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	var request resetPasswordRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		log.Error("failed to parse json body", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.authService.ResetPassword(request.Token, request.NewPassword); err != nil {
+		if errors.Is(err, service.ErrInvalidResetToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Reset link is invalid or has expired"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
+func (c *authController) ChangePassword(ctx *gin.Context) {
+	const fn = "adapters.controller.ChangePassword"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var request changePasswordRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		log.Error("failed to parse json body", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	jwtTokenPair, err := c.authService.ChangePassword(userID, request.CurrentPassword, request.NewPassword, clientInfo(ctx))
+	if err != nil {
+		var rateErr *service.RateLimitError
+		if errors.As(err, &rateErr) {
+			tooManyRequests(ctx, rateErr)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidPassword) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+		if errors.Is(err, service.ErrUserNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, JWTTokenResponse{
+		AccessToken:  jwtTokenPair.AccessToken,
+		RefreshToken: jwtTokenPair.RefreshToken,
+	})
+}
@@ -13,3 +13,35 @@ type loginRequest struct {
 type refreshRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// Code is a code of the authenticator app or a recovery code
+	Code string `json:"code" binding:"required"`
+}
+
+type grantRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
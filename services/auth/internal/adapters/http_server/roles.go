@@ -0,0 +1,85 @@
+package http_server
+
+import (
+	"airbnb-clone/auth/internal/adapters/http_server/middleware"
+	"airbnb-clone/auth/internal/domain/service"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (c *authController) GetUserRoles(ctx *gin.Context) {
+	adminID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	roles, err := c.authService.GetUserRoles(adminID, ctx.Param("id"))
+	if err != nil {
+		roleError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+func (c *authController) GrantRole(ctx *gin.Context) {
+	const fn = "adapters.controller.GrantRole"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	adminID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var request grantRoleRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		log.Error("failed to parse json body", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.authService.GrantRole(adminID, ctx.Param("id"), request.Role); err != nil {
+		roleError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
+func (c *authController) RevokeRole(ctx *gin.Context) {
+	adminID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := c.authService.RevokeRole(adminID, ctx.Param("id"), ctx.Param("role")); err != nil {
+		roleError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
+// roleError maps errors of the role endpoints to a response
+func roleError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to manage roles"})
+	case errors.Is(err, service.ErrInvalidRole):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Role cannot be granted or revoked"})
+	case errors.Is(err, service.ErrUserNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, service.ErrRoleNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User does not have the role"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,14 +1,41 @@
 package http_server
 
 import (
+	"airbnb-clone/auth/internal/adapters/http_server/middleware"
+
 	"github.com/gin-gonic/gin"
 )
 
-func SetupAuthRoutes(r *gin.Engine, authController AuthController) {
+func SetupAuthRoutes(r *gin.Engine, authController AuthController, validator middleware.TokenValidator) {
+	r.GET("/.well-known/jwks.json", authController.JWKS)
+
 	urlGroup := r.Group("/auth")
 	{
 		urlGroup.POST("/register", authController.Register)
 		urlGroup.POST("/login", authController.Login)
+		urlGroup.POST("/login/mfa", authController.LoginMFA)
 		urlGroup.POST("/refresh", authController.Refresh)
+		urlGroup.POST("/logout", authController.Logout)
+		urlGroup.POST("/verify-email", authController.VerifyEmail)
+		urlGroup.POST("/password/forgot", authController.ForgotPassword)
+		urlGroup.POST("/password/reset", authController.ResetPassword)
+		urlGroup.GET("/oidc/:provider/login", authController.OIDCLogin)
+		urlGroup.GET("/oidc/:provider/callback", authController.OIDCCallback)
+	}
+
+	authGroup := r.Group("/auth")
+	authGroup.Use(middleware.AuthMiddleware(validator))
+	{
+		authGroup.POST("/logout-all", authController.LogoutAll)
+		authGroup.GET("/sessions", authController.GetSessions)
+		authGroup.DELETE("/sessions/:id", authController.RevokeSession)
+		authGroup.POST("/verify-email/resend", authController.ResendVerificationEmail)
+		authGroup.POST("/password/change", authController.ChangePassword)
+		authGroup.POST("/mfa/totp/enroll", authController.EnrollTOTP)
+		authGroup.POST("/mfa/totp/confirm", authController.ConfirmTOTP)
+		authGroup.POST("/mfa/totp/disable", authController.DisableTOTP)
+		authGroup.GET("/admin/users/:id/roles", authController.GetUserRoles)
+		authGroup.POST("/admin/users/:id/roles", authController.GrantRole)
+		authGroup.DELETE("/admin/users/:id/roles/:role", authController.RevokeRole)
 	}
 }
@@ -0,0 +1,86 @@
+package http_server
+
+import (
+	"airbnb-clone/auth/internal/adapters/http_server/middleware"
+	"airbnb-clone/auth/internal/domain/service"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (c *authController) Logout(ctx *gin.Context) {
+	const fn = "adapters.controller.Logout"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	var request refreshRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		log.Error("failed to parse json body", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.authService.Logout(request.RefreshToken); err != nil {
+		if errors.Is(err, service.ErrRefreshTokenNotFound) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
+func (c *authController) LogoutAll(ctx *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := c.authService.LogoutAll(userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
+func (c *authController) GetSessions(ctx *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	sessions, err := c.authService.GetSessions(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+func (c *authController) RevokeSession(ctx *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := c.authService.RevokeSession(userID, ctx.Param("id")); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
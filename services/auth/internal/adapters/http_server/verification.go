@@ -0,0 +1,73 @@
+package http_server
+
+import (
+	"airbnb-clone/auth/internal/adapters/http_server/middleware"
+	"airbnb-clone/auth/internal/domain/service"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (c *authController) VerifyEmail(ctx *gin.Context) {
+	const fn = "adapters.controller.VerifyEmail"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	var request verifyEmailRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		log.Error("failed to parse json body", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.authService.VerifyEmail(request.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidVerification) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Verification link is invalid or has expired"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// access tokens issued before carry email_verified=false until refreshed
+	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
+func (c *authController) ResendVerificationEmail(ctx *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := c.authService.ResendVerificationEmail(userID); err != nil {
+		if errors.Is(err, service.ErrEmailAlreadyVerified) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+			return
+		}
+		if errors.Is(err, service.ErrUserNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		var rateErr *service.RateLimitError
+		if errors.As(err, &rateErr) {
+			tooManyRequests(ctx, rateErr)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"status": "OK"})
+}
+
+func tooManyRequests(ctx *gin.Context, err *service.RateLimitError) {
+	seconds := int(math.Ceil(err.RetryAfter.Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(max(seconds, 1)))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later"})
+}
@@ -0,0 +1,32 @@
+package keys
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWKSet is the document served at /.well-known/jwks.json (RFC 7517)
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func newRSAJWK(kid string, public *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: AlgorithmRS256,
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+	}
+}
@@ -0,0 +1,235 @@
+package keys
+
+import (
+	"airbnb-clone/auth/internal/adapters/repository"
+	"airbnb-clone/auth/internal/config"
+	"airbnb-clone/auth/internal/domain/entity"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+
+	rsaKeyBits = 2048
+)
+
+var ErrNoSigningKey = errors.New("no active signing key")
+
+type key struct {
+	id          string
+	activatesAt time.Time
+	private     *rsa.PrivateKey
+}
+
+// Manager holds the signing keys shared by all auth instances through the
+// database. It creates the next key PublishAhead before it starts signing and
+// keeps the previous one published for Overlap afterwards
+type Manager struct {
+	repo repository.AuthRepository
+	cfg  config.JWT
+	log  *slog.Logger
+
+	mu   sync.RWMutex
+	keys []key // newest activation first
+}
+
+func NewManager(repo repository.AuthRepository, cfg config.JWT, log *slog.Logger) *Manager {
+	return &Manager{repo: repo, cfg: cfg, log: log}
+}
+
+// Init creates the first key when there is none and loads the published keys
+func (m *Manager) Init() error {
+	const fn = "adapters.keys.Init"
+
+	if err := m.rotate(time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if err := m.reload(time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+// Run rotates keys when due and picks up keys created by other instances
+// until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	const fn = "adapters.keys.Run"
+	log := m.log.With(slog.String("fn", fn))
+
+	ticker := time.NewTicker(m.cfg.KeyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if err := m.rotate(now); err != nil {
+				log.Error("failed to rotate signing keys", slog.String("error", err.Error()))
+			}
+			if err := m.reload(now); err != nil {
+				log.Error("failed to reload signing keys", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// SigningKey returns the key tokens are signed with: the newest active one
+func (m *Manager) SigningKey() (string, *rsa.PrivateKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	for _, k := range m.keys {
+		if !k.activatesAt.After(now) {
+			return k.id, k.private, nil
+		}
+	}
+
+	return "", nil, ErrNoSigningKey
+}
+
+// PublicKey returns a published key by its kid
+func (m *Manager) PublicKey(kid string) (*rsa.PublicKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, k := range m.keys {
+		if k.id == kid {
+			return &k.private.PublicKey, true
+		}
+	}
+
+	return nil, false
+}
+
+// JWKS returns every published public key, including the upcoming one
+func (m *Manager) JWKS() JWKSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(m.keys))}
+	for _, k := range m.keys {
+		set.Keys = append(set.Keys, newRSAJWK(k.id, &k.private.PublicKey))
+	}
+
+	return set
+}
+
+// rotate creates the next key once the current one is due for replacement.
+// Instances racing here may both create a key, which is harmless: both are
+// published and the newest activation signs
+func (m *Manager) rotate(now time.Time) error {
+	published, err := m.repo.GetPublishedSigningKeys(now)
+	if err != nil {
+		return err
+	}
+
+	if len(published) == 0 {
+		return m.createKey(now)
+	}
+
+	newest := published[0]
+	if newest.ActivatesAt.After(now) {
+		return nil // the next key is already waiting
+	}
+
+	activatesAt := newest.ActivatesAt.Add(m.cfg.KeyRotationInterval)
+	if now.Before(activatesAt.Add(-m.cfg.KeyPublishAhead)) {
+		return nil
+	}
+	if activatesAt.Before(now.Add(m.cfg.KeyPublishAhead)) {
+		activatesAt = now.Add(m.cfg.KeyPublishAhead)
+	}
+
+	return m.repo.WithinTransaction(func(repo repository.AuthRepository) error {
+		if err := repo.ExpireSigningKey(newest.ID, activatesAt.Add(m.cfg.KeyOverlap)); err != nil {
+			return err
+		}
+		return m.createKeyWith(repo, activatesAt)
+	})
+}
+
+func (m *Manager) createKey(activatesAt time.Time) error {
+	return m.createKeyWith(m.repo, activatesAt)
+}
+
+func (m *Manager) createKeyWith(repo repository.AuthRepository, activatesAt time.Time) error {
+	private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+
+	signingKey := &entity.SigningKey{
+		ID:          uuid.New().String(),
+		Algorithm:   AlgorithmRS256,
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ActivatesAt: activatesAt,
+	}
+	if err := repo.CreateSigningKey(signingKey); err != nil {
+		return err
+	}
+
+	m.log.Info("created signing key", slog.String("kid", signingKey.ID), slog.Time("activates_at", activatesAt))
+	return nil
+}
+
+func (m *Manager) reload(now time.Time) error {
+	published, err := m.repo.GetPublishedSigningKeys(now)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]key, 0, len(published))
+	for _, signingKey := range published {
+		private, err := parsePrivateKey(signingKey.PrivateKey)
+		if err != nil {
+			m.log.Error("skipping unreadable signing key", slog.String("kid", signingKey.ID), slog.String("error", err.Error()))
+			continue
+		}
+		keys = append(keys, key{id: signingKey.ID, activatesAt: signingKey.ActivatesAt, private: private})
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.mu.Unlock()
+
+	return nil
+}
+
+func parsePrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid PEM block")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+
+	return private, nil
+}
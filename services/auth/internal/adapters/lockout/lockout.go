@@ -0,0 +1,145 @@
+package lockout
+
+import (
+	"airbnb-clone/auth/internal/config"
+	"airbnb-clone/auth/internal/domain/entity"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Store keeps the failed login counters. The repository implements it on
+// Postgres; MemoryStore keeps them in process
+type Store interface {
+	GetLoginAttempt(subject string) (entity.LoginAttempt, error)
+	RecordLoginFailure(subject string, now time.Time, resetBefore time.Time) (int, error)
+	LockLogin(subject string, until time.Time) error
+	ResetLoginAttempts(subject string) error
+}
+
+type policy struct {
+	freeAttempts int
+	baseDelay    time.Duration
+	maxDelay     time.Duration
+}
+
+// lockFor returns how long the subject is locked after the given number of
+// failures in a row
+func (p policy) lockFor(failures int) time.Duration {
+	over := failures - p.freeAttempts
+	if over <= 0 {
+		return 0
+	}
+
+	delay := p.baseDelay
+	for i := 1; i < over && delay < p.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.maxDelay)
+}
+
+// Limiter tracks failed logins per account and per client IP
+type Limiter struct {
+	store      Store
+	account    policy
+	ip         policy
+	resetAfter time.Duration
+}
+
+func NewLimiter(store Store, cfg config.Lockout) *Limiter {
+	return &Limiter{
+		store:      store,
+		account:    policy{freeAttempts: cfg.AccountFreeAttempts, baseDelay: cfg.AccountBaseDelay, maxDelay: cfg.AccountMaxDelay},
+		ip:         policy{freeAttempts: cfg.IPFreeAttempts, baseDelay: cfg.IPBaseDelay, maxDelay: cfg.IPMaxDelay},
+		resetAfter: cfg.ResetAfter,
+	}
+}
+
+// NewStore returns the store selected by cfg.Store, falling back to the
+// repository
+func NewStore(cfg config.Lockout, repo Store) (Store, error) {
+	const fn = "adapters.lockout.NewStore"
+
+	switch cfg.Store {
+	case "postgres", "":
+		return repo, nil
+	case "memory":
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown lockout store %q", fn, cfg.Store)
+	}
+}
+
+// RetryAfter returns how long logins for the email from the IP are locked, or
+// zero if they are allowed. The email does not have to belong to an account
+func (l *Limiter) RetryAfter(email string, ip string, now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	for _, subject := range l.subjects(email, ip) {
+		attempt, err := l.store.GetLoginAttempt(subject)
+		if err != nil {
+			return 0, err
+		}
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			wait = max(wait, attempt.LockedUntil.Sub(now))
+		}
+	}
+	return wait, nil
+}
+
+// RecordFailure counts a failed login for the email and the IP and locks them
+// once they ran out of free attempts
+func (l *Limiter) RecordFailure(email string, ip string, now time.Time) error {
+	var errs []error
+	for _, subject := range l.subjects(email, ip) {
+		errs = append(errs, l.recordFailure(subject, l.policyOf(subject), now))
+	}
+	return errors.Join(errs...)
+}
+
+// Unlock forgets the failures of the account, after a successful login or a
+// password reset. The failures of the IP are kept, a valid login must not hide
+// guessing at other accounts
+func (l *Limiter) Unlock(email string) error {
+	return l.store.ResetLoginAttempts(accountSubject(email))
+}
+
+func (l *Limiter) recordFailure(subject string, p policy, now time.Time) error {
+	failures, err := l.store.RecordLoginFailure(subject, now, now.Add(-l.resetAfter))
+	if err != nil {
+		return err
+	}
+
+	if lock := p.lockFor(failures); lock > 0 {
+		return l.store.LockLogin(subject, now.Add(lock))
+	}
+	return nil
+}
+
+func (l *Limiter) subjects(email string, ip string) []string {
+	subjects := []string{accountSubject(email)}
+	if ip != "" {
+		subjects = append(subjects, ipSubject(ip))
+	}
+	return subjects
+}
+
+func (l *Limiter) policyOf(subject string) policy {
+	if strings.HasPrefix(subject, ipPrefix) {
+		return l.ip
+	}
+	return l.account
+}
+
+const (
+	accountPrefix = "account:"
+	ipPrefix      = "ip:"
+)
+
+func accountSubject(email string) string {
+	return accountPrefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipSubject(ip string) string {
+	return ipPrefix + ip
+}
@@ -0,0 +1,63 @@
+package lockout
+
+import (
+	"airbnb-clone/auth/internal/domain/entity"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the counters in process. It is meant for tests and single
+// instance setups, the counters are lost on restart
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]entity.LoginAttempt
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: make(map[string]entity.LoginAttempt)}
+}
+
+func (m *MemoryStore) GetLoginAttempt(subject string) (entity.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.attempts[subject], nil
+}
+
+func (m *MemoryStore) RecordLoginFailure(subject string, now time.Time, resetBefore time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt, ok := m.attempts[subject]
+	if !ok || attempt.LastFailureAt.Before(resetBefore) {
+		attempt = entity.LoginAttempt{Subject: subject, LockedUntil: attempt.LockedUntil}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	m.attempts[subject] = attempt
+
+	return attempt.Failures, nil
+}
+
+func (m *MemoryStore) LockLogin(subject string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt, ok := m.attempts[subject]
+	if !ok {
+		return nil
+	}
+	if attempt.LockedUntil == nil || attempt.LockedUntil.Before(until) {
+		attempt.LockedUntil = &until
+		m.attempts[subject] = attempt
+	}
+	return nil
+}
+
+func (m *MemoryStore) ResetLoginAttempts(subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, subject)
+	return nil
+}
@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LogMailer is meant for local development: it logs every message and, when dir
+// is set, also writes it there as an .eml file
+type LogMailer struct {
+	from string
+	dir  string
+	log  *slog.Logger
+}
+
+func NewLogMailer(from string, dir string, log *slog.Logger) *LogMailer {
+	return &LogMailer{from: from, dir: dir, log: log}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	const fn = "adapters.mailer.LogMailer.Send"
+
+	body, err := compose(m.from, msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	m.log.Info("mail sent", slog.String("fn", fn), slog.String("to", msg.To),
+		slog.String("subject", msg.Subject), slog.String("body", msg.Body))
+
+	if m.dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.New().String())
+	if err := os.WriteFile(filepath.Join(m.dir, name), body, 0o644); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"airbnb-clone/auth/internal/config"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"strings"
+	"time"
+)
+
+var ErrInvalidMessage = errors.New("invalid mail message")
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails to users
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns the mailer selected by cfg.Backend: "smtp" or "log"
+func New(cfg config.Mail, log *slog.Logger) (Mailer, error) {
+	const fn = "adapters.mailer.New"
+
+	switch cfg.Backend {
+	case "smtp":
+		return NewSMTPMailer(cfg.From, cfg.SMTP), nil
+	case "log", "":
+		return NewLogMailer(cfg.From, cfg.Dir, log), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown mail backend %q", fn, cfg.Backend)
+	}
+}
+
+// compose renders msg as an RFC 5322 message. Header values are checked for
+// line breaks so that user input cannot inject headers
+func compose(from string, msg Message, now time.Time) ([]byte, error) {
+	for _, value := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, ErrInvalidMessage
+		}
+	}
+	if msg.To == "" {
+		return nil, ErrInvalidMessage
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"airbnb-clone/auth/internal/config"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPMailer sends mail through an SMTP relay. smtp.SendMail upgrades the
+// connection with STARTTLS when the server offers it
+type SMTPMailer struct {
+	from string
+	addr string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(from string, cfg config.SMTP) *SMTPMailer {
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &SMTPMailer{
+		from: from,
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		auth: auth,
+	}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	const fn = "adapters.mailer.SMTPMailer.Send"
+
+	body, err := compose(m.from, msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, body); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
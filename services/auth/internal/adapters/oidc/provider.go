@@ -0,0 +1,255 @@
+package oidc
+
+import (
+	"airbnb-clone/auth/internal/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// clockSkew is tolerated on the time claims of ID tokens
+const clockSkew = time.Minute
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrExchangeFailed  = errors.New("authorization code exchange failed")
+	ErrInvalidIDToken  = errors.New("invalid id token")
+)
+
+// Identity is the user an ID token was issued for
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// Registry holds the configured providers by name
+type Registry struct {
+	providers map[string]*Provider
+}
+
+func NewRegistry(cfg config.OIDC) *Registry {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	providers := make(map[string]*Provider, len(cfg.Providers))
+	for name, providerCfg := range cfg.Providers {
+		if providerCfg.Issuer == "" || providerCfg.ClientID == "" {
+			continue
+		}
+		providers[name] = &Provider{
+			name:         name,
+			cfg:          providerCfg,
+			clientSecret: os.Getenv(providerCfg.ClientSecretEnv),
+			client:       client,
+		}
+	}
+
+	return &Registry{providers: providers}
+}
+
+func (r *Registry) Provider(name string) (*Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID Connect issuer we act as a relying party for. Its
+// endpoints are discovered on first use
+type Provider struct {
+	name         string
+	cfg          config.OIDCProvider
+	clientSecret string
+	client       *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      *keySet
+}
+
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// AuthCodeURL returns the URL the user is sent to for an authorization code
+// flow with PKCE
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, codeVerifier string) (string, error) {
+	const fn = "adapters.oidc.AuthCodeURL"
+
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", fn, err)
+	}
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email"}
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return doc.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange trades the authorization code for tokens and returns the verified
+// identity of the ID token
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*Identity, error) {
+	const fn = "adapters.oidc.Exchange"
+
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.clientSecret != "" {
+		form.Set("client_secret", p.clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %w: status %d", fn, ErrExchangeFailed, resp.StatusCode)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%s: %w: no id_token in response", fn, ErrExchangeFailed)
+	}
+
+	identity, err := p.verifyIDToken(ctx, doc, tokens.IDToken, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return identity, nil
+}
+
+// verifyIDToken checks the signature and the claims required by OpenID Connect
+// Core section 3.1.3.7
+func (p *Provider) verifyIDToken(ctx context.Context, doc *discovery, rawToken string, nonce string) (*Identity, error) {
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg()}, SkipClaimsValidation: true}
+
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keySet().key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	now := time.Now()
+	switch {
+	case claims["iss"] != doc.Issuer:
+		return nil, fmt.Errorf("%w: issuer", ErrInvalidIDToken)
+	case !claims.VerifyAudience(p.cfg.ClientID, true):
+		return nil, fmt.Errorf("%w: audience", ErrInvalidIDToken)
+	case !claims.VerifyExpiresAt(now.Add(-clockSkew).Unix(), true):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	case !claims.VerifyIssuedAt(now.Add(clockSkew).Unix(), true):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	case claims["nonce"] != nonce:
+		return nil, fmt.Errorf("%w: nonce", ErrInvalidIDToken)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: subject", ErrInvalidIDToken)
+	}
+	email, _ := claims["email"].(string)
+
+	return &Identity{Subject: subject, Email: email, EmailVerified: isTrue(claims["email_verified"])}, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var doc discovery
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, p.client, wellKnown, &doc); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if doc.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery: issuer %q does not match %q", doc.Issuer, p.cfg.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("discovery: missing endpoints")
+	}
+
+	p.discovery = &doc
+	p.keys = newKeySet(doc.JWKSURI, p.client)
+	return p.discovery, nil
+}
+
+func (p *Provider) keySet() *keySet {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.keys
+}
+
+// isTrue reads a boolean claim. Some providers send email_verified as a string
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,101 @@
+package oidc_test
+
+import (
+	"airbnb-clone/auth/internal/adapters/oidc"
+	"airbnb-clone/auth/internal/adapters/oidc/oidctest"
+	"airbnb-clone/auth/internal/config"
+	"context"
+	"errors"
+	"testing"
+)
+
+const clientID = "airbnb-clone"
+
+var testUser = oidctest.User{Subject: "sub-1", Email: "guest@example.com", EmailVerified: true}
+
+func newTestProvider(t *testing.T) (*oidc.Provider, *oidctest.Issuer) {
+	t.Helper()
+
+	issuer, err := oidctest.NewIssuer()
+	if err != nil {
+		t.Fatalf("start issuer: %v", err)
+	}
+	t.Cleanup(issuer.Close)
+
+	registry := oidc.NewRegistry(config.OIDC{Providers: map[string]config.OIDCProvider{
+		"test": {Issuer: issuer.URL(), ClientID: clientID, RedirectURL: "http://localhost:3000/oidc/callback"},
+	}})
+	provider, err := registry.Provider("test")
+	if err != nil {
+		t.Fatalf("provider: %v", err)
+	}
+
+	return provider, issuer
+}
+
+// authorize starts a login with fresh values and returns the code the issuer
+// redirected back with
+func authorize(t *testing.T, provider *oidc.Provider, issuer *oidctest.Issuer, nonce string, verifier string) string {
+	t.Helper()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", nonce, verifier)
+	if err != nil {
+		t.Fatalf("auth code url: %v", err)
+	}
+	code, _, err := issuer.Authorize(authURL, testUser)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	return code
+}
+
+func TestExchange(t *testing.T) {
+	tests := []struct {
+		name     string
+		nonce    string
+		verifier string
+		audience string
+		wantErr  error
+	}{
+		{name: "valid", nonce: "nonce", verifier: "verifier"},
+		{name: "nonce mismatch", nonce: "other-nonce", verifier: "verifier", wantErr: oidc.ErrInvalidIDToken},
+		{name: "pkce verifier mismatch", nonce: "nonce", verifier: "other-verifier", wantErr: oidc.ErrExchangeFailed},
+		{name: "audience mismatch", nonce: "nonce", verifier: "verifier", audience: "another-client", wantErr: oidc.ErrInvalidIDToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, issuer := newTestProvider(t)
+			issuer.Audience = tt.audience
+			code := authorize(t, provider, issuer, "nonce", "verifier")
+
+			identity, err := provider.Exchange(context.Background(), code, tt.verifier, tt.nonce)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("exchange: %v", err)
+			}
+
+			want := oidc.Identity{Subject: testUser.Subject, Email: testUser.Email, EmailVerified: true}
+			if *identity != want {
+				t.Errorf("identity = %+v, want %+v", *identity, want)
+			}
+		})
+	}
+}
+
+func TestExchangeCodeIsSingleUse(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	code := authorize(t, provider, issuer, "nonce", "verifier")
+
+	if _, err := provider.Exchange(context.Background(), code, "verifier", "nonce"); err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if _, err := provider.Exchange(context.Background(), code, "verifier", "nonce"); !errors.Is(err, oidc.ErrExchangeFailed) {
+		t.Fatalf("second exchange: got %v, want %v", err, oidc.ErrExchangeFailed)
+	}
+}
@@ -5,5 +5,22 @@ import "errors"
 var (
 	ErrEmailNotFound        = errors.New("user with this email not found")
 	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenConsumed = errors.New("refresh token already consumed or revoked")
+	ErrSessionNotFound      = errors.New("session not found")
 	ErrEmailExist           = errors.New("provided email is already exists")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrVerificationNotFound = errors.New("email verification token not found")
+	ErrVerificationUsed     = errors.New("email verification token already used")
+	ErrResetTokenNotFound   = errors.New("password reset token not found")
+	ErrResetTokenUsed       = errors.New("password reset token already used")
+	ErrTOTPAlreadyEnabled   = errors.New("two-factor authentication already enabled")
+	ErrTOTPNotPending       = errors.New("no pending two-factor enrollment")
+	ErrTOTPCodeReused       = errors.New("two-factor code already used")
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+	ErrChallengeNotFound    = errors.New("mfa challenge not found")
+	ErrChallengeExhausted   = errors.New("mfa challenge used or out of attempts")
+	ErrRoleNotFound         = errors.New("user does not have the role")
+	ErrOIDCStateNotFound    = errors.New("oidc login state not found")
+	ErrIdentityNotFound     = errors.New("external identity not found")
+	ErrIdentityExists       = errors.New("external identity already linked")
 )
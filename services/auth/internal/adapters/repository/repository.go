@@ -5,18 +5,65 @@ import (
 	domain "airbnb-clone/auth/internal/domain/entity"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
 type AuthRepository interface {
 	CreateNewUser(user *domain.UserCredentials) (string, error)
 	GetUserByEmail(email string) (*domain.UserCredentials, error)
+	GetUserByID(id string) (*domain.UserCredentials, error)
+	GetUsersByIDs(ids []string) ([]domain.UserCredentials, error)
+	CreateEmailVerificationToken(token *domain.EmailVerificationToken) error
+	GetEmailVerificationToken(tokenHash string) (domain.EmailVerificationToken, error)
+	UseEmailVerificationToken(id string) error
+	MarkEmailVerified(userID string) error
+	GetRecentEmailVerificationTokens(userID string, since time.Time) ([]domain.EmailVerificationToken, error)
+	UpdatePassword(userID string, passwordHash string) error
+	CreatePasswordResetToken(token *domain.PasswordResetToken) error
+	GetPasswordResetToken(tokenHash string) (domain.PasswordResetToken, error)
+	UsePasswordResetToken(id string) error
+	InvalidatePasswordResetTokens(userID string) error
+	CountRecentPasswordResetTokens(userID string, since time.Time) (int64, error)
+	SetTOTPSecret(userID string, secret string) error
+	EnableTOTP(userID string, step int64) error
+	DisableTOTP(userID string) error
+	AdvanceTOTPStep(userID string, step int64) error
+	ReplaceRecoveryCodes(userID string, codeHashes []string) error
+	UseRecoveryCode(userID string, codeHash string) error
+	CreateMFAChallenge(challenge *domain.MFAChallenge) error
+	GetMFAChallenge(tokenHash string) (domain.MFAChallenge, error)
+	RecordMFAChallengeAttempt(id string, maxAttempts int) error
+	UseMFAChallenge(id string) error
+	GetLoginAttempt(subject string) (domain.LoginAttempt, error)
+	RecordLoginFailure(subject string, now time.Time, resetBefore time.Time) (int, error)
+	LockLogin(subject string, until time.Time) error
+	ResetLoginAttempts(subject string) error
+	GetUserRoles(userID string) ([]string, error)
+	GetRolesOfUsers(userIDs []string) (map[string][]string, error)
+	GrantRole(role *domain.UserRole) error
+	RevokeRole(userID string, role string) error
+	CreateOIDCLoginState(state *domain.OIDCLoginState) error
+	TakeOIDCLoginState(stateHash string) (domain.OIDCLoginState, error)
+	GetExternalIdentity(provider string, subject string) (*domain.ExternalIdentity, error)
+	CreateExternalIdentity(identity *domain.ExternalIdentity) error
 	CreateRefreshToken(token *domain.RefreshToken) error
 	ValidateRefreshToken(tokenValue string) (domain.RefreshToken, error)
+	ConsumeRefreshToken(id string) error
+	RevokeTokenFamily(familyID string) error
+	RevokeUserSession(userID string, familyID string) error
+	RevokeAllUserTokens(userID string) error
+	GetActiveSessions(userID string) ([]domain.Session, error)
+	IsSessionActive(familyID string) (bool, error)
+	CreateSigningKey(key *domain.SigningKey) error
+	GetPublishedSigningKeys(now time.Time) ([]domain.SigningKey, error)
+	ExpireSigningKey(id string, expiresAt time.Time) error
+	WithinTransaction(fn func(repo AuthRepository) error) error
 }
 
 type storage struct {
@@ -36,7 +83,9 @@ func New(cfg *config.Config) (AuthRepository, error) {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	err = db.AutoMigrate(&domain.UserCredentials{}, &domain.RefreshToken{})
+	err = db.AutoMigrate(&domain.UserCredentials{}, &domain.RefreshToken{}, &domain.SigningKey{}, &domain.EmailVerificationToken{},
+		&domain.PasswordResetToken{}, &domain.RecoveryCode{}, &domain.MFAChallenge{}, &domain.LoginAttempt{},
+		&domain.UserRole{}, &domain.ExternalIdentity{}, &domain.OIDCLoginState{})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
@@ -75,6 +124,527 @@ func (s *storage) GetUserByEmail(email string) (*domain.UserCredentials, error)
 	return user, nil
 }
 
+// GetUsersByIDs returns the users that exist, in no particular order
+func (s *storage) GetUsersByIDs(ids []string) ([]domain.UserCredentials, error) {
+	const fn = "adapters.repository.GetUsersByIDs"
+
+	var users []domain.UserCredentials
+	if err := s.db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("%s: database error: %w", fn, err)
+	}
+
+	return users, nil
+}
+
+func (s *storage) GetUserByID(id string) (*domain.UserCredentials, error) {
+	const fn = "adapters.repository.GetUserByID"
+	var user domain.UserCredentials
+
+	result := s.db.Where("id = ?", id).First(&user)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return &domain.UserCredentials{}, ErrUserNotFound
+		}
+
+		return &domain.UserCredentials{}, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return &user, nil
+}
+
+func (s *storage) CreateEmailVerificationToken(token *domain.EmailVerificationToken) error {
+	const fn = "adapters.repository.CreateEmailVerificationToken"
+
+	if err := s.db.Create(token).Error; err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+func (s *storage) GetEmailVerificationToken(tokenHash string) (domain.EmailVerificationToken, error) {
+	const fn = "adapters.repository.GetEmailVerificationToken"
+
+	var token domain.EmailVerificationToken
+	result := s.db.Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return domain.EmailVerificationToken{}, ErrVerificationNotFound
+		}
+
+		return domain.EmailVerificationToken{}, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return token, nil
+}
+
+// UseEmailVerificationToken marks the token as used. Like ConsumeRefreshToken
+// it only matches an unused token, so a token can be redeemed once
+func (s *storage) UseEmailVerificationToken(id string) error {
+	const fn = "adapters.repository.UseEmailVerificationToken"
+
+	result := s.db.Model(&domain.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrVerificationUsed
+	}
+	return nil
+}
+
+func (s *storage) MarkEmailVerified(userID string) error {
+	const fn = "adapters.repository.MarkEmailVerified"
+
+	result := s.db.Model(&domain.UserCredentials{}).
+		Where("id = ? AND email_verified = ?", userID, false).
+		Updates(map[string]interface{}{"email_verified": true, "email_verified_at": time.Now()})
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	return nil
+}
+
+// GetRecentEmailVerificationTokens returns the tokens sent to the user since the
+// given time, newest first
+func (s *storage) GetRecentEmailVerificationTokens(userID string, since time.Time) ([]domain.EmailVerificationToken, error) {
+	const fn = "adapters.repository.GetRecentEmailVerificationTokens"
+
+	var tokens []domain.EmailVerificationToken
+	result := s.db.Where("user_id = ? AND created_at > ?", userID, since).
+		Order("created_at DESC").
+		Find(&tokens)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return tokens, nil
+}
+
+func (s *storage) UpdatePassword(userID string, passwordHash string) error {
+	const fn = "adapters.repository.UpdatePassword"
+
+	result := s.db.Model(&domain.UserCredentials{}).Where("id = ?", userID).Update("password", passwordHash)
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *storage) CreatePasswordResetToken(token *domain.PasswordResetToken) error {
+	const fn = "adapters.repository.CreatePasswordResetToken"
+
+	if err := s.db.Create(token).Error; err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+func (s *storage) GetPasswordResetToken(tokenHash string) (domain.PasswordResetToken, error) {
+	const fn = "adapters.repository.GetPasswordResetToken"
+
+	var token domain.PasswordResetToken
+	result := s.db.Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return domain.PasswordResetToken{}, ErrResetTokenNotFound
+		}
+
+		return domain.PasswordResetToken{}, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return token, nil
+}
+
+// UsePasswordResetToken marks the token as used. It only matches an unused
+// token, so a token can be redeemed once
+func (s *storage) UsePasswordResetToken(id string) error {
+	const fn = "adapters.repository.UsePasswordResetToken"
+
+	result := s.db.Model(&domain.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrResetTokenUsed
+	}
+	return nil
+}
+
+// InvalidatePasswordResetTokens marks every outstanding reset token of the user
+// as used
+func (s *storage) InvalidatePasswordResetTokens(userID string) error {
+	const fn = "adapters.repository.InvalidatePasswordResetTokens"
+
+	result := s.db.Model(&domain.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	return nil
+}
+
+func (s *storage) CountRecentPasswordResetTokens(userID string, since time.Time) (int64, error) {
+	const fn = "adapters.repository.CountRecentPasswordResetTokens"
+
+	var count int64
+	result := s.db.Model(&domain.PasswordResetToken{}).
+		Where("user_id = ? AND created_at > ?", userID, since).
+		Count(&count)
+	if result.Error != nil {
+		return 0, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return count, nil
+}
+
+// SetTOTPSecret stores the secret of a new enrollment, replacing an unconfirmed
+// one. It fails with ErrTOTPAlreadyEnabled once 2FA is on
+func (s *storage) SetTOTPSecret(userID string, secret string) error {
+	const fn = "adapters.repository.SetTOTPSecret"
+
+	result := s.db.Model(&domain.UserCredentials{}).
+		Where("id = ? AND totp_enabled = ?", userID, false).
+		Update("totp_secret", secret)
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+// EnableTOTP turns 2FA on for a pending enrollment. step is the time step of
+// the confirmation code
+func (s *storage) EnableTOTP(userID string, step int64) error {
+	const fn = "adapters.repository.EnableTOTP"
+
+	result := s.db.Model(&domain.UserCredentials{}).
+		Where("id = ? AND totp_enabled = ? AND totp_secret <> ''", userID, false).
+		Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step})
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrTOTPNotPending
+	}
+	return nil
+}
+
+// DisableTOTP turns 2FA off and removes the secret and the recovery codes
+func (s *storage) DisableTOTP(userID string) error {
+	const fn = "adapters.repository.DisableTOTP"
+
+	result := s.db.Model(&domain.UserCredentials{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0})
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if err := s.db.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+// AdvanceTOTPStep records the time step of an accepted code. A step that is not
+// newer than the last one fails with ErrTOTPCodeReused
+func (s *storage) AdvanceTOTPStep(userID string, step int64) error {
+	const fn = "adapters.repository.AdvanceTOTPStep"
+
+	result := s.db.Model(&domain.UserCredentials{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrTOTPCodeReused
+	}
+	return nil
+}
+
+func (s *storage) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	const fn = "adapters.repository.ReplaceRecoveryCodes"
+
+	if err := s.db.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	codes := make([]domain.RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, domain.RecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	if err := s.db.Create(&codes).Error; err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+func (s *storage) UseRecoveryCode(userID string, codeHash string) error {
+	const fn = "adapters.repository.UseRecoveryCode"
+
+	result := s.db.Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+func (s *storage) CreateMFAChallenge(challenge *domain.MFAChallenge) error {
+	const fn = "adapters.repository.CreateMFAChallenge"
+
+	if err := s.db.Create(challenge).Error; err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+func (s *storage) GetMFAChallenge(tokenHash string) (domain.MFAChallenge, error) {
+	const fn = "adapters.repository.GetMFAChallenge"
+
+	var challenge domain.MFAChallenge
+	result := s.db.Where("token_hash = ?", tokenHash).First(&challenge)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return domain.MFAChallenge{}, ErrChallengeNotFound
+		}
+
+		return domain.MFAChallenge{}, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return challenge, nil
+}
+
+// RecordMFAChallengeAttempt counts an attempt to answer the challenge. It fails
+// with ErrChallengeExhausted when the challenge was used or has no attempts left
+func (s *storage) RecordMFAChallengeAttempt(id string, maxAttempts int) error {
+	const fn = "adapters.repository.RecordMFAChallengeAttempt"
+
+	result := s.db.Model(&domain.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL AND attempts < ?", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrChallengeExhausted
+	}
+	return nil
+}
+
+func (s *storage) UseMFAChallenge(id string) error {
+	const fn = "adapters.repository.UseMFAChallenge"
+
+	result := s.db.Model(&domain.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrChallengeExhausted
+	}
+	return nil
+}
+
+// GetLoginAttempt returns the failures of the subject, or a zero value if it has
+// none
+func (s *storage) GetLoginAttempt(subject string) (domain.LoginAttempt, error) {
+	const fn = "adapters.repository.GetLoginAttempt"
+
+	var attempt domain.LoginAttempt
+	result := s.db.Where("subject = ?", subject).Limit(1).Find(&attempt)
+	if result.Error != nil {
+		return domain.LoginAttempt{}, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return attempt, nil
+}
+
+// RecordLoginFailure counts a failed login and returns the number of failures
+// in a row. Failures older than resetBefore are forgotten. The upsert keeps
+// the count right under concurrent logins
+func (s *storage) RecordLoginFailure(subject string, now time.Time, resetBefore time.Time) (int, error) {
+	const fn = "adapters.repository.RecordLoginFailure"
+
+	var failures int
+	result := s.db.Raw(`INSERT INTO login_attempts (subject, failures, last_failure_at) VALUES (?, 1, ?)
+		ON CONFLICT (subject) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures`, subject, now, resetBefore).Scan(&failures)
+	if result.Error != nil {
+		return 0, fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	return failures, nil
+}
+
+// LockLogin locks the subject until the given time. A longer lock in place is
+// kept
+func (s *storage) LockLogin(subject string, until time.Time) error {
+	const fn = "adapters.repository.LockLogin"
+
+	result := s.db.Model(&domain.LoginAttempt{}).
+		Where("subject = ? AND (locked_until IS NULL OR locked_until < ?)", subject, until).
+		Update("locked_until", until)
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	return nil
+}
+
+func (s *storage) ResetLoginAttempts(subject string) error {
+	const fn = "adapters.repository.ResetLoginAttempts"
+
+	if err := s.db.Where("subject = ?", subject).Delete(&domain.LoginAttempt{}).Error; err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+// GetUserRoles returns the stored roles of the user, sorted
+func (s *storage) GetUserRoles(userID string) ([]string, error) {
+	const fn = "adapters.repository.GetUserRoles"
+
+	var roles []string
+	result := s.db.Model(&domain.UserRole{}).
+		Where("user_id = ?", userID).
+		Order("role").
+		Pluck("role", &roles)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return roles, nil
+}
+
+// GetRolesOfUsers returns the granted roles of every user that has any
+func (s *storage) GetRolesOfUsers(userIDs []string) (map[string][]string, error) {
+	const fn = "adapters.repository.GetRolesOfUsers"
+
+	var userRoles []domain.UserRole
+	result := s.db.Where("user_id IN ?", userIDs).Order("role").Find(&userRoles)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	roles := make(map[string][]string, len(userIDs))
+	for _, userRole := range userRoles {
+		roles[userRole.UserID] = append(roles[userRole.UserID], userRole.Role)
+	}
+	return roles, nil
+}
+
+// GrantRole stores the role. Granting a role the user has is a no-op
+func (s *storage) GrantRole(role *domain.UserRole) error {
+	const fn = "adapters.repository.GrantRole"
+
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(role).Error; err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+func (s *storage) RevokeRole(userID string, role string) error {
+	const fn = "adapters.repository.RevokeRole"
+
+	result := s.db.Where("user_id = ? AND role = ?", userID, role).Delete(&domain.UserRole{})
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// CreateOIDCLoginState stores the state and drops expired ones of logins that
+// were never completed
+func (s *storage) CreateOIDCLoginState(state *domain.OIDCLoginState) error {
+	const fn = "adapters.repository.CreateOIDCLoginState"
+
+	if err := s.db.Where("expires_at < ?", time.Now()).Delete(&domain.OIDCLoginState{}).Error; err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := s.db.Create(state).Error; err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+// TakeOIDCLoginState deletes the state and returns it, so that a callback can
+// only be completed once
+func (s *storage) TakeOIDCLoginState(stateHash string) (domain.OIDCLoginState, error) {
+	const fn = "adapters.repository.TakeOIDCLoginState"
+
+	var states []domain.OIDCLoginState
+	result := s.db.Clauses(clause.Returning{}).Where("state_hash = ?", stateHash).Delete(&states)
+	if result.Error != nil {
+		return domain.OIDCLoginState{}, fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if len(states) == 0 {
+		return domain.OIDCLoginState{}, ErrOIDCStateNotFound
+	}
+	return states[0], nil
+}
+
+func (s *storage) GetExternalIdentity(provider string, subject string) (*domain.ExternalIdentity, error) {
+	const fn = "adapters.repository.GetExternalIdentity"
+
+	var identity domain.ExternalIdentity
+	result := s.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrIdentityNotFound
+		}
+
+		return nil, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return &identity, nil
+}
+
+func (s *storage) CreateExternalIdentity(identity *domain.ExternalIdentity) error {
+	const fn = "adapters.repository.CreateExternalIdentity"
+
+	var pgErr *pgconn.PgError
+	if err := s.db.Create(identity).Error; err != nil {
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrIdentityExists
+		}
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
 func (s *storage) CreateRefreshToken(token *domain.RefreshToken) error {
 	const fn = "adapters.repository.CreateRefreshToken"
 
@@ -101,3 +671,142 @@ func (s *storage) ValidateRefreshToken(tokenValue string) (domain.RefreshToken,
 
 	return token, nil
 }
+
+// ConsumeRefreshToken marks the token as exchanged. The update only matches a
+// live token, so of two concurrent refreshes with the same token one gets
+// ErrRefreshTokenConsumed
+func (s *storage) ConsumeRefreshToken(id string) error {
+	const fn = "adapters.repository.ConsumeRefreshToken"
+
+	result := s.db.Model(&domain.RefreshToken{}).
+		Where("id = ? AND consumed_at IS NULL AND revoked_at IS NULL", id).
+		Update("consumed_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrRefreshTokenConsumed
+	}
+	return nil
+}
+
+func (s *storage) RevokeTokenFamily(familyID string) error {
+	const fn = "adapters.repository.RevokeTokenFamily"
+
+	result := s.db.Model(&domain.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	return nil
+}
+
+// RevokeUserSession revokes a session of the user. Sessions of other users are
+// reported as not found
+func (s *storage) RevokeUserSession(userID string, familyID string) error {
+	const fn = "adapters.repository.RevokeUserSession"
+
+	result := s.db.Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND family_id = ? AND revoked_at IS NULL AND consumed_at IS NULL AND expires_at > ?", userID, familyID, time.Now()).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *storage) RevokeAllUserTokens(userID string) error {
+	const fn = "adapters.repository.RevokeAllUserTokens"
+
+	result := s.db.Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	return nil
+}
+
+// GetActiveSessions lists the live token of every family of the user, most
+// recently used first
+func (s *storage) GetActiveSessions(userID string) ([]domain.Session, error) {
+	const fn = "adapters.repository.GetActiveSessions"
+
+	var sessions []domain.Session
+	result := s.db.Model(&domain.RefreshToken{}).
+		Select(`family_id AS id, user_agent, ip, created_at AS last_used_at, expires_at,
+			(SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = refresh_tokens.family_id) AS created_at`).
+		Where("user_id = ? AND revoked_at IS NULL AND consumed_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("refresh_tokens.created_at DESC").
+		Scan(&sessions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return sessions, nil
+}
+
+// IsSessionActive reports whether the family still has a live token, i.e. the
+// session was neither revoked nor has expired
+func (s *storage) IsSessionActive(familyID string) (bool, error) {
+	const fn = "adapters.repository.IsSessionActive"
+
+	var count int64
+	result := s.db.Model(&domain.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL AND consumed_at IS NULL AND expires_at > ?", familyID, time.Now()).
+		Count(&count)
+	if result.Error != nil {
+		return false, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return count > 0, nil
+}
+
+func (s *storage) CreateSigningKey(key *domain.SigningKey) error {
+	const fn = "adapters.repository.CreateSigningKey"
+
+	if err := s.db.Create(key).Error; err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+// GetPublishedSigningKeys returns the keys that have not expired yet, newest
+// activation first
+func (s *storage) GetPublishedSigningKeys(now time.Time) ([]domain.SigningKey, error) {
+	const fn = "adapters.repository.GetPublishedSigningKeys"
+
+	var keys []domain.SigningKey
+	result := s.db.Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("activates_at DESC").
+		Find(&keys)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return keys, nil
+}
+
+func (s *storage) ExpireSigningKey(id string, expiresAt time.Time) error {
+	const fn = "adapters.repository.ExpireSigningKey"
+
+	result := s.db.Model(&domain.SigningKey{}).Where("id = ?", id).Update("expires_at", expiresAt)
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	return nil
+}
+
+// WithinTransaction runs fn with a repository bound to a single transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise
+func (s *storage) WithinTransaction(fn func(repo AuthRepository) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&storage{db: tx})
+	})
+}
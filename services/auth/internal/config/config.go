@@ -12,12 +12,25 @@ type Config struct {
 	Env             string `yaml:"env" env-default:"local"`
 	HttpServer      `yaml:"http_server"`
 	PostgresConnect `yaml:"postgres_storage"`
+	JWT             `yaml:"jwt"`
+	Mail            `yaml:"mail"`
+	Verification    `yaml:"email_verification"`
+	PasswordReset   `yaml:"password_reset"`
+	MFA             `yaml:"mfa"`
+	Lockout         `yaml:"lockout"`
+	RBAC            `yaml:"rbac"`
+	OIDC            `yaml:"oidc"`
+	GRPC            `yaml:"grpc"`
 }
 
 type HttpServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// TrustedProxies are the CIDRs of the reverse proxies in front of the
+	// service. X-Forwarded-For is only believed when the request comes from one
+	// of them; empty trusts no proxy and the client IP is the peer address
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" env-separator:","`
 }
 
 type PostgresConnect struct {
@@ -28,6 +41,124 @@ type PostgresConnect struct {
 	DatabaseName string `yaml:"dbname"  env-required:"true"`
 }
 
+type JWT struct {
+	AccessTTL  time.Duration `yaml:"access_ttl" env-default:"15m"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl" env-default:"168h"`
+	// Issuer and Audience are stamped into every token and must match the
+	// auth settings of the other services
+	Issuer    string        `yaml:"issuer" env-default:"airbnb-clone-auth"`
+	Audience  string        `yaml:"audience" env-default:"airbnb-clone"`
+	ClockSkew time.Duration `yaml:"clock_skew" env-default:"30s"`
+	// A new signing key is created every KeyRotationInterval and published
+	// KeyPublishAhead before it signs, which must exceed the JWKS cache TTL of
+	// the other services. The retired key stays published for KeyOverlap,
+	// which must exceed AccessTTL
+	KeyRotationInterval time.Duration `yaml:"key_rotation_interval" env-default:"720h"`
+	KeyPublishAhead     time.Duration `yaml:"key_publish_ahead" env-default:"10m"`
+	KeyOverlap          time.Duration `yaml:"key_overlap" env-default:"1h"`
+	KeyCheckInterval    time.Duration `yaml:"key_check_interval" env-default:"1m"`
+}
+
+type Mail struct {
+	// Backend is "smtp" or "log". The log mailer only logs messages and, if Dir
+	// is set, writes them there
+	Backend string `yaml:"backend" env-default:"log"`
+	From    string `yaml:"from" env-default:"noreply@airbnb-clone.local"`
+	Dir     string `yaml:"dir"`
+	SMTP    SMTP   `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host" env-default:"localhost"`
+	Port     int    `yaml:"port" env-default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+}
+
+type Verification struct {
+	// LinkURL is the page the emailed link points to; the token is appended as
+	// the token query parameter
+	LinkURL  string        `yaml:"link_url" env-default:"http://localhost:3000/verify-email"`
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"24h"`
+	// A user may ask for another email after ResendCooldown, and for at most
+	// MaxEmails emails per EmailWindow
+	ResendCooldown time.Duration `yaml:"resend_cooldown" env-default:"1m"`
+	MaxEmails      int           `yaml:"max_emails" env-default:"5"`
+	EmailWindow    time.Duration `yaml:"email_window" env-default:"1h"`
+}
+
+type PasswordReset struct {
+	// LinkURL is the page the emailed link points to; the token is appended as
+	// the token query parameter
+	LinkURL  string        `yaml:"link_url" env-default:"http://localhost:3000/reset-password"`
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"1h"`
+	// Further requests within EmailWindow are silently dropped once MaxEmails
+	// have been sent
+	MaxEmails   int           `yaml:"max_emails" env-default:"3"`
+	EmailWindow time.Duration `yaml:"email_window" env-default:"1h"`
+}
+
+type MFA struct {
+	// Issuer is the account name authenticator apps show
+	Issuer       string        `yaml:"issuer" env-default:"Airbnb Clone"`
+	ChallengeTTL time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+	// MaxAttempts is the number of codes that may be tried per challenge
+	MaxAttempts   int `yaml:"max_attempts" env-default:"5"`
+	RecoveryCodes int `yaml:"recovery_codes" env-default:"10"`
+}
+
+// Lockout throttles failed logins per account and per client IP. After the
+// free attempts every failure locks the subject for twice as long as the one
+// before, starting at the base delay and capped at the max delay. Failures are
+// forgotten ResetAfter after the last one
+type Lockout struct {
+	// Store is "postgres", or "memory" for a single instance
+	Store               string        `yaml:"store" env-default:"postgres"`
+	AccountFreeAttempts int           `yaml:"account_free_attempts" env-default:"5"`
+	AccountBaseDelay    time.Duration `yaml:"account_base_delay" env-default:"30s"`
+	AccountMaxDelay     time.Duration `yaml:"account_max_delay" env-default:"1h"`
+	IPFreeAttempts      int           `yaml:"ip_free_attempts" env-default:"20"`
+	IPBaseDelay         time.Duration `yaml:"ip_base_delay" env-default:"10s"`
+	IPMaxDelay          time.Duration `yaml:"ip_max_delay" env-default:"15m"`
+	ResetAfter          time.Duration `yaml:"reset_after" env-default:"24h"`
+}
+
+type RBAC struct {
+	// BootstrapAdmin is granted the admin role on startup and when they verify
+	// their email, so that the first admin can grant roles to others
+	BootstrapAdmin string `yaml:"bootstrap_admin" env:"BOOTSTRAP_ADMIN_EMAIL"`
+}
+
+// OIDC configures social login. Each provider is an OpenID Connect issuer
+// keyed by the name used in the login URL, e.g. /auth/oidc/google/login
+type OIDC struct {
+	// StateTTL bounds the time between starting a login and the callback
+	StateTTL  time.Duration           `yaml:"state_ttl" env-default:"10m"`
+	Providers map[string]OIDCProvider `yaml:"providers"`
+}
+
+type OIDCProvider struct {
+	Issuer   string `yaml:"issuer"`
+	ClientID string `yaml:"client_id"`
+	// ClientSecretEnv names the environment variable holding the client
+	// secret, so that it stays out of the config file
+	ClientSecretEnv string   `yaml:"client_secret_env"`
+	RedirectURL     string   `yaml:"redirect_url"`
+	Scopes          []string `yaml:"scopes"`
+}
+
+// GRPC configures the internal API for the other services. It uses TLS once
+// CertFile and KeyFile are set, and also requires client certificates signed
+// by ClientCAFile once that is set. Without all three it only listens on
+// localhost
+type GRPC struct {
+	// ListenAddress is empty to disable the API
+	ListenAddress string `yaml:"address" env-default:"localhost:9000"`
+	CertFile      string `yaml:"cert_file" env:"GRPC_CERT_FILE"`
+	KeyFile       string `yaml:"key_file" env:"GRPC_KEY_FILE"`
+	ClientCAFile  string `yaml:"client_ca_file" env:"GRPC_CLIENT_CA_FILE"`
+}
+
 func MustLoad() *Config {
 	configPath := "config/local.yaml"
 
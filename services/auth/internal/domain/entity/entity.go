@@ -11,9 +11,17 @@ import (
 )
 
 type UserCredentials struct {
-	ID       string `gorm:"type:uuid;primaryKey"`
-	Email    string `gorm:"uniqueIndex;not null"`
-	Password string `gorm:"size:255;not null"`
+	ID              string `gorm:"type:uuid;primaryKey"`
+	Email           string `gorm:"uniqueIndex;not null"`
+	Password        string `gorm:"size:255;not null"`
+	EmailVerified   bool   `gorm:"not null;default:false"`
+	EmailVerifiedAt *time.Time
+	// TOTPSecret is set on enrollment; 2FA is only enforced once the user
+	// confirmed it with a first code. TOTPLastStep is the time step of the last
+	// accepted code, so that a code cannot be replayed
+	TOTPSecret   string `gorm:"size:64"`
+	TOTPEnabled  bool   `gorm:"not null;default:false"`
+	TOTPLastStep int64  `gorm:"not null;default:0"`
 }
 
 func (u *UserCredentials) BeforeCreate(tx *gorm.DB) error {
@@ -24,9 +32,21 @@ func (u *UserCredentials) BeforeCreate(tx *gorm.DB) error {
 }
 
 type RefreshToken struct {
-	ID        string    `gorm:"type:uuid;primaryKey"`
-	TokenHash string    `gorm:"type:varchar(255);not null;uniqueIndex"` // Hashed token
-	UserID    string    `gorm:"type:uuid;not null;index"`
+	ID        string `gorm:"type:uuid;primaryKey"`
+	TokenHash string `gorm:"type:varchar(255);not null;uniqueIndex"` // Hashed token
+	UserID    string `gorm:"type:uuid;not null;index"`
+	// FamilyID is shared by every token rotated from the same login
+	FamilyID string `gorm:"type:uuid;not null;index;default:gen_random_uuid()"`
+	// ConsumedAt is set once the token has been exchanged for a new pair.
+	// Presenting a consumed token again means it was stolen
+	ConsumedAt *time.Time
+	RevokedAt  *time.Time
+	// AuthMethods are the amr values of the login the family started with,
+	// space separated
+	AuthMethods string `gorm:"size:64"`
+	// UserAgent and IP describe the client the token was last issued to
+	UserAgent string    `gorm:"size:512"`
+	IP        string    `gorm:"size:64"`
 	ExpiresAt time.Time `gorm:"not null"`
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
@@ -36,6 +56,9 @@ func (r *RefreshToken) BeforeCreate(tx *gorm.DB) error {
 	if r.ID == "" {
 		r.ID = uuid.New().String()
 	}
+	if r.FamilyID == "" {
+		r.FamilyID = uuid.New().String()
+	}
 	return nil
 }
 
@@ -47,3 +70,167 @@ func (r *RefreshToken) HashToken(token string) {
 func (r *RefreshToken) IsValid() bool {
 	return time.Now().Before(r.ExpiresAt)
 }
+
+func (r *RefreshToken) IsConsumed() bool {
+	return r.ConsumedAt != nil
+}
+
+func (r *RefreshToken) IsRevoked() bool {
+	return r.RevokedAt != nil
+}
+
+// EmailVerificationToken is a single-use token mailed to a user to prove they
+// own their email address. Only its hash is stored
+type EmailVerificationToken struct {
+	ID        string    `gorm:"type:uuid;primaryKey"`
+	UserID    string    `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"type:varchar(255);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+func (t *EmailVerificationToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (t *EmailVerificationToken) IsValid() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+// PasswordResetToken is a single-use token mailed to a user who forgot their
+// password. Only its hash is stored
+type PasswordResetToken struct {
+	ID        string    `gorm:"type:uuid;primaryKey"`
+	UserID    string    `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"type:varchar(255);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+func (t *PasswordResetToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (t *PasswordResetToken) IsValid() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+// RecoveryCode lets a user with 2FA log in without their authenticator. Only
+// its hash is stored and it can be used once
+type RecoveryCode struct {
+	ID        string `gorm:"type:uuid;primaryKey"`
+	UserID    string `gorm:"type:uuid;not null;index"`
+	CodeHash  string `gorm:"type:varchar(255);not null;uniqueIndex"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (c *RecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// MFAChallenge is issued when the first factor of a user with 2FA, a password
+// or a social login, was correct. It is exchanged for tokens together with a
+// code, and only allows a few attempts
+type MFAChallenge struct {
+	ID        string `gorm:"type:uuid;primaryKey"`
+	UserID    string `gorm:"type:uuid;not null;index"`
+	TokenHash string `gorm:"type:varchar(255);not null;uniqueIndex"`
+	// AuthMethods are the amr values of the first factor, space separated.
+	// The second factor adds its own. Challenges stored before the column
+	// existed came from password logins
+	AuthMethods string    `gorm:"size:64;not null;default:'pwd'"`
+	Attempts    int       `gorm:"not null;default:0"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	UsedAt      *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+func (c *MFAChallenge) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (c *MFAChallenge) IsValid() bool {
+	return c.UsedAt == nil && time.Now().Before(c.ExpiresAt)
+}
+
+// LoginAttempt counts the failed logins of an account or of a client IP.
+// Subject is prefixed with the kind, e.g. "account:" or "ip:"
+type LoginAttempt struct {
+	Subject       string    `gorm:"size:320;primaryKey"`
+	Failures      int       `gorm:"not null;default:0"`
+	LastFailureAt time.Time `gorm:"not null"`
+	LockedUntil   *time.Time
+}
+
+// ExternalIdentity links an account of an OpenID Connect provider to a user
+type ExternalIdentity struct {
+	ID        string    `gorm:"type:uuid;primaryKey"`
+	UserID    string    `gorm:"type:uuid;not null;index"`
+	Provider  string    `gorm:"size:64;not null;uniqueIndex:idx_provider_subject"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_provider_subject"`
+	Email     string    `gorm:"size:320"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (i *ExternalIdentity) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// OIDCLoginState is kept between redirecting the user to a provider and the
+// callback. It is looked up by the hash of the state parameter and used once
+type OIDCLoginState struct {
+	StateHash    string    `gorm:"type:varchar(255);primaryKey"`
+	Provider     string    `gorm:"size:64;not null"`
+	Nonce        string    `gorm:"size:128;not null"`
+	CodeVerifier string    `gorm:"size:128;not null"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+// ClientInfo identifies the device a session was opened from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// Session is one login of a user: the live token of a refresh token family.
+// Its ID is the family ID, which stays the same across rotations
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// SigningKey is an RSA key pair used to sign tokens. A key is published in the
+// JWKS from creation until ExpiresAt and signs tokens from ActivatesAt until a
+// newer key activates. The gap lets verifiers fetch a key before they see it
+// and keep verifying tokens it signed after it retires
+type SigningKey struct {
+	ID          string    `gorm:"size:64;primaryKey"` // kid header
+	Algorithm   string    `gorm:"size:16;not null"`
+	PrivateKey  string    `gorm:"type:text;not null"` // PKCS#8 PEM
+	ActivatesAt time.Time `gorm:"not null;index"`
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
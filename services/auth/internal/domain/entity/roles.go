@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"slices"
+	"time"
+)
+
+// Every user is a guest; other roles are granted by an admin
+const (
+	RoleGuest = "guest"
+	RoleHost  = "host"
+	RoleAdmin = "admin"
+)
+
+// Permissions are checked by the other services, they are embedded in access
+// tokens next to the roles
+const (
+	PermissionCreateBooking    = "bookings:create"
+	PermissionCreateListing    = "listings:create"
+	PermissionModerateListings = "listings:moderate"
+	PermissionModerateProfiles = "profiles:moderate"
+	PermissionManageRoles      = "roles:manage"
+)
+
+var rolePermissions = map[string][]string{
+	RoleGuest: {PermissionCreateBooking},
+	RoleHost:  {PermissionCreateListing},
+	RoleAdmin: {PermissionModerateListings, PermissionModerateProfiles, PermissionManageRoles},
+}
+
+// UserRole grants a role to a user. The guest role is implicit and not stored
+type UserRole struct {
+	UserID    string    `gorm:"type:uuid;primaryKey"`
+	Role      string    `gorm:"size:32;primaryKey"`
+	GrantedBy string    `gorm:"type:uuid"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// PermissionsOf returns the sorted permissions of the roles
+func PermissionsOf(roles []string) []string {
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, rolePermissions[role]...)
+	}
+	slices.Sort(permissions)
+	return slices.Compact(permissions)
+}
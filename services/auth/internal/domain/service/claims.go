@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+var (
+	errMissingClaim    = errors.New("missing claim")
+	errTokenExpired    = errors.New("token has expired")
+	errTokenNotYet     = errors.New("token is not valid yet")
+	errInvalidIssuer   = errors.New("issuer is not accepted")
+	errInvalidAudience = errors.New("audience is not accepted")
+	errWrongTokenType  = errors.New("wrong token type")
+)
+
+// tokenClaims are the claims of both token types, told apart by typ so that a
+// refresh token is never accepted in place of an access token
+type tokenClaims struct {
+	UserID string `json:"user_id"`
+	Type   string `json:"typ"`
+	// EmailVerified is only set on access tokens
+	EmailVerified bool `json:"email_verified,omitempty"`
+	// AuthMethods is the amr claim (RFC 8176), only set on access tokens
+	AuthMethods []string `json:"amr,omitempty"`
+	// Roles and the Permissions they grant, only set on access tokens. Changes
+	// show up in the tokens issued by the next refresh
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	// SessionID is the refresh token family the access token was issued with,
+	// so that introspection can tell a revoked session
+	SessionID string `json:"sid,omitempty"`
+	jwt.StandardClaims
+}
+
+// newTokenParser only accepts RS256; time claims are checked by
+// validateClaims, with the clock skew applied
+func newTokenParser() *jwt.Parser {
+	return &jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg()}, SkipClaimsValidation: true}
+}
+
+func (s *authService) newClaims(userID string, tokenType string, now time.Time, expiresAt time.Time) tokenClaims {
+	return tokenClaims{
+		UserID: userID,
+		Type:   tokenType,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    s.jwtConfig.Issuer,
+			Audience:  s.jwtConfig.Audience,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+}
+
+func (s *authService) validateClaims(claims *tokenClaims, tokenType string, now time.Time) error {
+	skew := s.jwtConfig.ClockSkew
+
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("%w: exp", errMissingClaim)
+	}
+	if claims.IssuedAt == 0 {
+		return fmt.Errorf("%w: iat", errMissingClaim)
+	}
+	if claims.UserID == "" {
+		return fmt.Errorf("%w: user_id", errMissingClaim)
+	}
+
+	if !now.Add(-skew).Before(time.Unix(claims.ExpiresAt, 0)) {
+		return errTokenExpired
+	}
+	if now.Add(skew).Before(time.Unix(claims.IssuedAt, 0)) {
+		return errTokenNotYet
+	}
+	if claims.NotBefore != 0 && now.Add(skew).Before(time.Unix(claims.NotBefore, 0)) {
+		return errTokenNotYet
+	}
+
+	if claims.Issuer != s.jwtConfig.Issuer {
+		return errInvalidIssuer
+	}
+	if claims.Audience != s.jwtConfig.Audience {
+		return errInvalidAudience
+	}
+	if claims.Type != tokenType {
+		return errWrongTokenType
+	}
+
+	return nil
+}
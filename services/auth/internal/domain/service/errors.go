@@ -1,11 +1,69 @@
 package service
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
-	ErrEmailNotFound        = errors.New("user with this email not found")
 	ErrRefreshTokenNotFound = errors.New("refresh token not found")
 	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+	ErrRefreshTokenRevoked  = errors.New("refresh token revoked")
+	ErrRefreshTokenReused   = errors.New("refresh token reuse detected")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrInvalidToken         = errors.New("invalid token")
 	ErrEmailExist           = errors.New("provided email is already exists")
 	ErrInvalidPassword      = errors.New("Invalid password")
+	ErrInvalidCredentials   = errors.New("invalid email or password")
+	ErrInvalidVerification  = errors.New("email verification token is invalid or expired")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrInvalidResetToken    = errors.New("password reset token is invalid or expired")
+	ErrTooManyRequests      = errors.New("too many requests")
+	ErrMFARequired          = errors.New("two-factor authentication required")
+	ErrMFAAlreadyEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled        = errors.New("two-factor authentication is not enabled")
+	ErrMFANotPending        = errors.New("two-factor authentication enrollment not started")
+	ErrInvalidMFACode       = errors.New("invalid two-factor code")
+	ErrInvalidMFAChallenge  = errors.New("mfa challenge is invalid or expired")
+	ErrForbidden            = errors.New("permission denied")
+	ErrInvalidRole          = errors.New("unknown or implicit role")
+	ErrRoleNotFound         = errors.New("user does not have the role")
+	ErrUnknownProvider      = errors.New("unknown identity provider")
+	ErrInvalidOIDCState     = errors.New("social login is invalid or expired")
+	ErrOIDCFailed           = errors.New("identity provider login failed")
+	ErrOIDCEmailNotVerified = errors.New("identity provider did not verify the email")
+	ErrIdentityConflict     = errors.New("external identity is already linked")
+	ErrAccountNotVerified   = errors.New("account with the email is not verified")
+	ErrTooManyUsers         = errors.New("too many users requested")
 )
+
+// MFARequiredError is returned by a login with the right password when the
+// user has 2FA enabled. The challenge token is exchanged for tokens together
+// with a code. It matches ErrMFARequired
+type MFARequiredError struct {
+	ChallengeToken string
+	ExpiresAt      time.Time
+}
+
+func (e *MFARequiredError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (e *MFARequiredError) Is(target error) bool {
+	return target == ErrMFARequired
+}
+
+// RateLimitError is returned when an action is refused because it was
+// attempted too often. It matches ErrTooManyRequests
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return ErrTooManyRequests.Error()
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrTooManyRequests
+}
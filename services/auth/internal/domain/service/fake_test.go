@@ -0,0 +1,377 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/adapters/keys"
+	"airbnb-clone/auth/internal/adapters/mailer"
+	"airbnb-clone/auth/internal/adapters/repository"
+	"airbnb-clone/auth/internal/config"
+	"airbnb-clone/auth/internal/domain/entity"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeData is the state shared by a fakeRepository and the transactions it
+// opens
+type fakeData struct {
+	// txMu is held for the whole of a transaction, so that other callers only
+	// see committed changes, as with the database
+	txMu sync.Mutex
+
+	users         map[string]*entity.UserCredentials
+	refreshTokens map[string]*entity.RefreshToken // by hash
+	signingKeys   []entity.SigningKey
+	oidcStates    map[string]entity.OIDCLoginState // by hash
+	identities    []entity.ExternalIdentity
+	mfaChallenges map[string]*entity.MFAChallenge           // by hash
+	verifications map[string]*entity.EmailVerificationToken // by hash
+	resetTokens   map[string]*entity.PasswordResetToken     // by hash
+	roles         map[string][]string                       // by user ID
+}
+
+// fakeRepository keeps the auth data in memory. Only the methods the tests
+// need are implemented, calling any other one panics
+type fakeRepository struct {
+	repository.AuthRepository
+	data *fakeData
+	inTx bool
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{data: &fakeData{
+		users:         make(map[string]*entity.UserCredentials),
+		refreshTokens: make(map[string]*entity.RefreshToken),
+		oidcStates:    make(map[string]entity.OIDCLoginState),
+		mfaChallenges: make(map[string]*entity.MFAChallenge),
+		verifications: make(map[string]*entity.EmailVerificationToken),
+		resetTokens:   make(map[string]*entity.PasswordResetToken),
+		roles:         make(map[string][]string),
+	}}
+}
+
+// lock serializes a call with the running transactions. Calls made within a
+// transaction already hold the lock
+func (r *fakeRepository) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.data.txMu.Lock()
+	return r.data.txMu.Unlock
+}
+
+func (r *fakeRepository) WithinTransaction(fn func(repo repository.AuthRepository) error) error {
+	defer r.lock()()
+	return fn(&fakeRepository{data: r.data, inTx: true})
+}
+
+func (r *fakeRepository) GetUserByID(id string) (*entity.UserCredentials, error) {
+	defer r.lock()()
+
+	user, ok := r.data.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeRepository) GetUserByEmail(email string) (*entity.UserCredentials, error) {
+	defer r.lock()()
+
+	for _, user := range r.data.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrEmailNotFound
+}
+
+func (r *fakeRepository) CreateNewUser(user *entity.UserCredentials) (string, error) {
+	defer r.lock()()
+
+	user.ID = uuid.New().String()
+	copied := *user
+	r.data.users[user.ID] = &copied
+	return user.ID, nil
+}
+
+func (r *fakeRepository) GetUserRoles(userID string) ([]string, error) {
+	defer r.lock()()
+
+	return append([]string(nil), r.data.roles[userID]...), nil
+}
+
+func (r *fakeRepository) GrantRole(role *entity.UserRole) error {
+	defer r.lock()()
+
+	if !slices.Contains(r.data.roles[role.UserID], role.Role) {
+		r.data.roles[role.UserID] = append(r.data.roles[role.UserID], role.Role)
+	}
+	return nil
+}
+
+func (r *fakeRepository) CreateEmailVerificationToken(token *entity.EmailVerificationToken) error {
+	defer r.lock()()
+
+	token.ID = uuid.New().String()
+	token.CreatedAt = time.Now()
+	copied := *token
+	r.data.verifications[token.TokenHash] = &copied
+	return nil
+}
+
+func (r *fakeRepository) GetEmailVerificationToken(tokenHash string) (entity.EmailVerificationToken, error) {
+	defer r.lock()()
+
+	token, ok := r.data.verifications[tokenHash]
+	if !ok {
+		return entity.EmailVerificationToken{}, repository.ErrVerificationNotFound
+	}
+	return *token, nil
+}
+
+func (r *fakeRepository) UseEmailVerificationToken(id string) error {
+	defer r.lock()()
+
+	for _, token := range r.data.verifications {
+		if token.ID == id && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrVerificationUsed
+}
+
+func (r *fakeRepository) MarkEmailVerified(userID string) error {
+	defer r.lock()()
+
+	user, ok := r.data.users[userID]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	now := time.Now()
+	user.EmailVerified = true
+	user.EmailVerifiedAt = &now
+	return nil
+}
+
+func (r *fakeRepository) CreatePasswordResetToken(token *entity.PasswordResetToken) error {
+	defer r.lock()()
+
+	token.ID = uuid.New().String()
+	token.CreatedAt = time.Now()
+	copied := *token
+	r.data.resetTokens[token.TokenHash] = &copied
+	return nil
+}
+
+func (r *fakeRepository) CountRecentPasswordResetTokens(userID string, since time.Time) (int64, error) {
+	defer r.lock()()
+
+	var count int64
+	for _, token := range r.data.resetTokens {
+		if token.UserID == userID && token.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeRepository) CreateRefreshToken(token *entity.RefreshToken) error {
+	defer r.lock()()
+
+	token.ID = uuid.New().String()
+	copied := *token
+	r.data.refreshTokens[token.TokenHash] = &copied
+	return nil
+}
+
+func (r *fakeRepository) ValidateRefreshToken(tokenValue string) (entity.RefreshToken, error) {
+	defer r.lock()()
+
+	token, ok := r.data.refreshTokens[tokenValue]
+	if !ok {
+		return entity.RefreshToken{}, repository.ErrRefreshTokenNotFound
+	}
+	return *token, nil
+}
+
+func (r *fakeRepository) ConsumeRefreshToken(id string) error {
+	defer r.lock()()
+
+	for _, token := range r.data.refreshTokens {
+		if token.ID == id && token.ConsumedAt == nil && token.RevokedAt == nil {
+			now := time.Now()
+			token.ConsumedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrRefreshTokenConsumed
+}
+
+func (r *fakeRepository) RevokeTokenFamily(familyID string) error {
+	defer r.lock()()
+
+	now := time.Now()
+	for _, token := range r.data.refreshTokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) CreateOIDCLoginState(state *entity.OIDCLoginState) error {
+	defer r.lock()()
+
+	r.data.oidcStates[state.StateHash] = *state
+	return nil
+}
+
+func (r *fakeRepository) TakeOIDCLoginState(stateHash string) (entity.OIDCLoginState, error) {
+	defer r.lock()()
+
+	state, ok := r.data.oidcStates[stateHash]
+	if !ok {
+		return entity.OIDCLoginState{}, repository.ErrOIDCStateNotFound
+	}
+	delete(r.data.oidcStates, stateHash)
+	return state, nil
+}
+
+func (r *fakeRepository) GetExternalIdentity(provider string, subject string) (*entity.ExternalIdentity, error) {
+	defer r.lock()()
+
+	for _, identity := range r.data.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, repository.ErrIdentityNotFound
+}
+
+func (r *fakeRepository) CreateExternalIdentity(identity *entity.ExternalIdentity) error {
+	defer r.lock()()
+
+	r.data.identities = append(r.data.identities, *identity)
+	return nil
+}
+
+func (r *fakeRepository) CreateMFAChallenge(challenge *entity.MFAChallenge) error {
+	defer r.lock()()
+
+	challenge.ID = uuid.New().String()
+	copied := *challenge
+	r.data.mfaChallenges[challenge.TokenHash] = &copied
+	return nil
+}
+
+func (r *fakeRepository) GetMFAChallenge(tokenHash string) (entity.MFAChallenge, error) {
+	defer r.lock()()
+
+	challenge, ok := r.data.mfaChallenges[tokenHash]
+	if !ok {
+		return entity.MFAChallenge{}, repository.ErrChallengeNotFound
+	}
+	return *challenge, nil
+}
+
+func (r *fakeRepository) RecordMFAChallengeAttempt(id string, maxAttempts int) error {
+	defer r.lock()()
+
+	for _, challenge := range r.data.mfaChallenges {
+		if challenge.ID == id && challenge.UsedAt == nil && challenge.Attempts < maxAttempts {
+			challenge.Attempts++
+			return nil
+		}
+	}
+	return repository.ErrChallengeExhausted
+}
+
+func (r *fakeRepository) UseMFAChallenge(id string) error {
+	defer r.lock()()
+
+	for _, challenge := range r.data.mfaChallenges {
+		if challenge.ID == id && challenge.UsedAt == nil {
+			now := time.Now()
+			challenge.UsedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrChallengeExhausted
+}
+
+func (r *fakeRepository) AdvanceTOTPStep(userID string, step int64) error {
+	defer r.lock()()
+
+	user := r.data.users[userID]
+	if user.TOTPLastStep >= step {
+		return repository.ErrTOTPCodeReused
+	}
+	user.TOTPLastStep = step
+	return nil
+}
+
+func (r *fakeRepository) CreateSigningKey(key *entity.SigningKey) error {
+	defer r.lock()()
+
+	r.data.signingKeys = append([]entity.SigningKey{*key}, r.data.signingKeys...)
+	return nil
+}
+
+func (r *fakeRepository) GetPublishedSigningKeys(time.Time) ([]entity.SigningKey, error) {
+	defer r.lock()()
+
+	return append([]entity.SigningKey(nil), r.data.signingKeys...), nil
+}
+
+// addUser stores a user with a verified email and returns it
+func (r *fakeRepository) addUser(email string) *entity.UserCredentials {
+	user := &entity.UserCredentials{ID: uuid.New().String(), Email: email, EmailVerified: true}
+	r.data.users[user.ID] = user
+	copied := *user
+	return &copied
+}
+
+// fakeMailer keeps the sent messages
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *fakeMailer) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *fakeMailer) messages() []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]mailer.Message(nil), m.sent...)
+}
+
+func newTestService(t *testing.T, repo *fakeRepository) *authService {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := config.JWT{AccessTTL: time.Minute, RefreshTTL: time.Hour, Issuer: "airbnb-clone-auth", Audience: "airbnb-clone",
+		KeyRotationInterval: time.Hour, KeyPublishAhead: time.Minute, KeyOverlap: time.Minute}
+
+	keyManager := keys.NewManager(repo, cfg, log)
+	if err := keyManager.Init(); err != nil {
+		t.Fatalf("init signing keys: %v", err)
+	}
+
+	return &authService{authRepository: repo, keys: keyManager, jwtConfig: cfg, tokenParser: newTokenParser(), log: log}
+}
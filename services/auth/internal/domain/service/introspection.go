@@ -0,0 +1,207 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/domain/entity"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxBatchUsers caps the ids of one BatchGetUsers call
+const MaxBatchUsers = 100
+
+// TokenIntrospection is what the other services learn about an access token.
+// Only Active is set for tokens that are not
+type TokenIntrospection struct {
+	Active        bool
+	UserID        string
+	SessionID     string
+	EmailVerified bool
+	// Roles and Permissions are the current ones, which may be newer than the
+	// ones in the token
+	Roles       []string
+	Permissions []string
+	AuthMethods []string
+	IssuedAt    time.Time
+	ExpiresAt   time.Time
+}
+
+// UserInfo is the account of a user as the other services see it
+type UserInfo struct {
+	ID            string
+	Email         string
+	EmailVerified bool
+	MFAEnabled    bool
+	Roles         []string
+	// LockedUntil is set while logins are locked after failed attempts
+	LockedUntil *time.Time
+}
+
+// Tell whether an access token is still active: validly signed, unexpired,
+// its session not revoked and its user not deleted. An inactive token is not
+// an error
+func (s *authService) IntrospectToken(accessToken string) (*TokenIntrospection, error) {
+	const fn = "domain.service.IntrospectToken"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	claims, err := s.parseAccessToken(accessToken)
+	if err != nil {
+		return &TokenIntrospection{}, nil
+	}
+
+	// tokens issued before sessions were stamped into them expire on their own
+	if claims.SessionID != "" {
+		active, err := s.authRepository.IsSessionActive(claims.SessionID)
+		if err != nil {
+			log.Error("failed to check session", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+			return nil, err
+		}
+		if !active {
+			return &TokenIntrospection{}, nil
+		}
+	}
+
+	user, err := s.getUser(claims.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return &TokenIntrospection{}, nil
+		}
+		log.Error("failed to get user", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return nil, err
+	}
+
+	roles, err := userRoles(s.authRepository, user.ID)
+	if err != nil {
+		log.Error("failed to get user roles", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return nil, err
+	}
+
+	return &TokenIntrospection{
+		Active:        true,
+		UserID:        user.ID,
+		SessionID:     claims.SessionID,
+		EmailVerified: user.EmailVerified,
+		Roles:         roles,
+		Permissions:   entity.PermissionsOf(roles),
+		AuthMethods:   claims.AuthMethods,
+		IssuedAt:      time.Unix(claims.IssuedAt, 0),
+		ExpiresAt:     time.Unix(claims.ExpiresAt, 0),
+	}, nil
+}
+
+// Return a user. Error can be ErrUserNotFound type
+func (s *authService) GetUser(userID string) (*UserInfo, error) {
+	const fn = "domain.service.GetUser"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	user, err := s.getUser(userID)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			log.Error("failed to get user", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		}
+		return nil, err
+	}
+
+	roles, err := userRoles(s.authRepository, user.ID)
+	if err != nil {
+		log.Error("failed to get user roles", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return nil, err
+	}
+
+	info, err := s.userInfo(user, roles)
+	if err != nil {
+		log.Error("failed to get login lock", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// Return the users that exist and the ids of the ones that do not. Error can
+// be ErrTooManyUsers type
+func (s *authService) BatchGetUsers(userIDs []string) ([]UserInfo, []string, error) {
+	const fn = "domain.service.BatchGetUsers"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	if len(userIDs) > MaxBatchUsers {
+		return nil, nil, ErrTooManyUsers
+	}
+
+	// malformed ids cannot exist, and would fail the uuid column comparison
+	var validIDs, missingIDs []string
+	for _, id := range userIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			missingIDs = append(missingIDs, id)
+			continue
+		}
+		validIDs = append(validIDs, id)
+	}
+	if len(validIDs) == 0 {
+		return []UserInfo{}, missingIDs, nil
+	}
+
+	users, err := s.authRepository.GetUsersByIDs(validIDs)
+	if err != nil {
+		log.Error("failed to get users", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return nil, nil, err
+	}
+	grantedRoles, err := s.authRepository.GetRolesOfUsers(validIDs)
+	if err != nil {
+		log.Error("failed to get user roles", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return nil, nil, err
+	}
+
+	found := make(map[string]UserInfo, len(users))
+	for i := range users {
+		roles := append([]string{entity.RoleGuest}, grantedRoles[users[i].ID]...)
+		info, err := s.userInfo(&users[i], roles)
+		if err != nil {
+			log.Error("failed to get login lock", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+			return nil, nil, err
+		}
+		found[users[i].ID] = *info
+	}
+
+	// keep the order of the request, duplicates included
+	infos := make([]UserInfo, 0, len(found))
+	for _, id := range validIDs {
+		info, ok := found[id]
+		if !ok {
+			missingIDs = append(missingIDs, id)
+			continue
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, missingIDs, nil
+}
+
+func (s *authService) userInfo(user *entity.UserCredentials, roles []string) (*UserInfo, error) {
+	info := &UserInfo{
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		MFAEnabled:    user.TOTPEnabled,
+		Roles:         roles,
+	}
+
+	now := time.Now()
+	lock, err := s.limiter.RetryAfter(user.Email, "", now)
+	if err != nil {
+		return nil, err
+	}
+	if lock > 0 {
+		lockedUntil := now.Add(lock)
+		info.LockedUntil = &lockedUntil
+	}
+
+	return info, nil
+}
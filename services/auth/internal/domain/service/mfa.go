@@ -0,0 +1,361 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/adapters/repository"
+	"airbnb-clone/auth/internal/adapters/totp"
+	"airbnb-clone/auth/internal/domain/entity"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// amr values (RFC 8176) of the ways a user can log in
+const (
+	amrPassword = "pwd"
+	amrOTP      = "otp"
+	amrMFA      = "mfa"
+	// amrFederated is not registered in RFC 8176, it marks a login through an
+	// external identity provider
+	amrFederated = "fed"
+)
+
+var (
+	passwordAuth  = []string{amrPassword}
+	federatedAuth = []string{amrFederated}
+	// the second factors add theirs to the amr values of the first factor
+	totpFactor         = []string{amrOTP, amrMFA}
+	recoveryCodeFactor = []string{amrMFA}
+)
+
+// recoveryAlphabet leaves out characters that are easily confused. It has 32
+// characters, so a random byte maps onto it without bias
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz023456789"
+
+// TOTPEnrollment is shown to the user once, usually as a QR code of the URI
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// Start 2FA enrollment with a new secret. 2FA is enforced once the user
+// confirms a code with ConfirmTOTP. Error can be ErrMFAAlreadyEnabled type
+func (s *authService) EnrollTOTP(userID string) (*TOTPEnrollment, error) {
+	const fn = "domain.service.EnrollTOTP"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Error("failed to generate secret", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return nil, err
+	}
+
+	if err := s.authRepository.SetTOTPSecret(user.ID, secret); err != nil {
+		if errors.Is(err, repository.ErrTOTPAlreadyEnabled) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		log.Error("failed to save secret", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return nil, err
+	}
+
+	return &TOTPEnrollment{Secret: secret, URI: totp.URI(s.mfaConfig.Issuer, user.Email, secret)}, nil
+}
+
+// Enable 2FA with the first code of the enrolled secret and return the
+// recovery codes, which are only shown this once. Wrong codes count towards
+// the login lockout. Error can be ErrMFANotPending, ErrInvalidMFACode or a
+// *RateLimitError
+func (s *authService) ConfirmTOTP(userID string, code string, client entity.ClientInfo) ([]string, error) {
+	const fn = "domain.service.ConfirmTOTP"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotPending
+	}
+	if err := s.checkCodeLockout(log, user.Email, client.IP); err != nil {
+		return nil, err
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		s.recordLoginFailure(log, user.Email, client.IP)
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		log.Error("failed to generate recovery codes", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return nil, err
+	}
+
+	err = s.authRepository.WithinTransaction(func(repo repository.AuthRepository) error {
+		if err := repo.EnableTOTP(user.ID, step); err != nil {
+			return err
+		}
+		return repo.ReplaceRecoveryCodes(user.ID, hashes)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrTOTPNotPending) {
+			return nil, ErrMFANotPending
+		}
+		log.Error("failed to enable 2FA", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "UserUUID", Value: slog.StringValue(user.ID)})
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Turn 2FA off. The user proves they still own the second factor with a code
+// or a recovery code. Wrong codes count towards the login lockout. Error can
+// be ErrMFANotEnabled, ErrInvalidMFACode or a *RateLimitError
+func (s *authService) DisableTOTP(userID string, code string, client entity.ClientInfo) error {
+	const fn = "domain.service.DisableTOTP"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	if err := s.checkCodeLockout(log, user.Email, client.IP); err != nil {
+		return err
+	}
+
+	err = s.authRepository.WithinTransaction(func(repo repository.AuthRepository) error {
+		if _, err := s.verifySecondFactor(repo, user, code); err != nil {
+			return err
+		}
+		return repo.DisableTOTP(user.ID)
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.recordLoginFailure(log, user.Email, client.IP)
+			return ErrInvalidMFACode
+		}
+		log.Error("failed to disable 2FA", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "UserUUID", Value: slog.StringValue(user.ID)})
+		return err
+	}
+
+	return nil
+}
+
+// Finish a login that returned MFARequiredError with a code or a recovery
+// code. Error can be ErrInvalidMFAChallenge, ErrInvalidMFACode or a
+// *RateLimitError
+func (s *authService) CompleteMFALogin(challengeToken string, code string, client entity.ClientInfo) (*JWTTokenPair, error) {
+	const fn = "domain.service.CompleteMFALogin"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	challenge, err := s.authRepository.GetMFAChallenge(hashToken(challengeToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrChallengeNotFound) {
+			return &JWTTokenPair{}, ErrInvalidMFAChallenge
+		}
+		log.Error("failed to get mfa challenge", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return &JWTTokenPair{}, err
+	}
+	if !challenge.IsValid() {
+		return &JWTTokenPair{}, ErrInvalidMFAChallenge
+	}
+
+	// the attempt is counted before the code is checked and outside of the
+	// transaction below, so that a wrong code still uses it up
+	if err := s.authRepository.RecordMFAChallengeAttempt(challenge.ID, s.mfaConfig.MaxAttempts); err != nil {
+		if errors.Is(err, repository.ErrChallengeExhausted) {
+			return &JWTTokenPair{}, ErrInvalidMFAChallenge
+		}
+		log.Error("failed to record mfa attempt", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return &JWTTokenPair{}, err
+	}
+
+	user, err := s.getUser(challenge.UserID)
+	if err != nil {
+		return &JWTTokenPair{}, err
+	}
+	if !user.TOTPEnabled {
+		return &JWTTokenPair{}, ErrInvalidMFAChallenge
+	}
+
+	// new challenges are cheap to get with the password, so wrong codes count
+	// towards the lockout of the account as well
+	retryAfter, err := s.limiter.RetryAfter(user.Email, client.IP, time.Now())
+	if err != nil {
+		log.Error("failed to check login lockout", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return &JWTTokenPair{}, err
+	}
+	if retryAfter > 0 {
+		return &JWTTokenPair{}, &RateLimitError{RetryAfter: retryAfter}
+	}
+
+	var jwtTokens *JWTTokenPair
+	err = s.authRepository.WithinTransaction(func(repo repository.AuthRepository) error {
+		secondFactor, err := s.verifySecondFactor(repo, user, code)
+		if err != nil {
+			return err
+		}
+		if err := repo.UseMFAChallenge(challenge.ID); err != nil {
+			return err
+		}
+
+		authMethods := append(strings.Fields(challenge.AuthMethods), secondFactor...)
+		jwtTokens, err = s.issueTokenPair(repo, user, "", authMethods, client)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.recordLoginFailure(log, user.Email, client.IP)
+			return &JWTTokenPair{}, ErrInvalidMFACode
+		}
+		if errors.Is(err, repository.ErrChallengeExhausted) {
+			return &JWTTokenPair{}, ErrInvalidMFAChallenge
+		}
+		log.Error("failed to complete mfa login", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "UserUUID", Value: slog.StringValue(user.ID)})
+		return &JWTTokenPair{}, err
+	}
+
+	s.unlockLogin(log, user.Email)
+	return jwtTokens, nil
+}
+
+// startMFAChallenge stores a new challenge for the user and returns it as a
+// *MFARequiredError. firstFactor are the amr values of the login so far
+func (s *authService) startMFAChallenge(user *entity.UserCredentials, firstFactor []string) error {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	challenge := &entity.MFAChallenge{
+		UserID:      user.ID,
+		TokenHash:   hashToken(token),
+		AuthMethods: strings.Join(firstFactor, " "),
+		ExpiresAt:   time.Now().Add(s.mfaConfig.ChallengeTTL),
+	}
+	if err := s.authRepository.CreateMFAChallenge(challenge); err != nil {
+		return err
+	}
+
+	return &MFARequiredError{ChallengeToken: token, ExpiresAt: challenge.ExpiresAt}
+}
+
+// checkCodeLockout returns a *RateLimitError while the account or the IP is
+// locked. Codes are only six digits, so without it a stolen access token would
+// be enough to guess them
+func (s *authService) checkCodeLockout(log *slog.Logger, email string, ip string) error {
+	retryAfter, err := s.limiter.RetryAfter(email, ip, time.Now())
+	if err != nil {
+		log.Error("failed to check login lockout", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
+	}
+	if retryAfter > 0 {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// verifySecondFactor accepts a TOTP code or a recovery code and returns the amr
+// values it adds to the first factor. Both are single use
+func (s *authService) verifySecondFactor(repo repository.AuthRepository, user *entity.UserCredentials, code string) ([]string, error) {
+	code = strings.TrimSpace(code)
+
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return nil, ErrInvalidMFACode
+		}
+		if err := repo.AdvanceTOTPStep(user.ID, step); err != nil {
+			if errors.Is(err, repository.ErrTOTPCodeReused) {
+				return nil, ErrInvalidMFACode
+			}
+			return nil, err
+		}
+		return totpFactor, nil
+	}
+
+	if err := repo.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code))); err != nil {
+		if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+			return nil, ErrInvalidMFACode
+		}
+		return nil, err
+	}
+	return recoveryCodeFactor, nil
+}
+
+// generateRecoveryCodes returns codes formatted as xxxxx-xxxxx and the hashes
+// to store
+func (s *authService) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, s.mfaConfig.RecoveryCodes)
+	hashes := make([]string, 0, s.mfaConfig.RecoveryCodes)
+
+	buf := make([]byte, 10)
+	for range s.mfaConfig.RecoveryCodes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+
+		var code strings.Builder
+		for i, b := range buf {
+			if i == len(buf)/2 {
+				code.WriteByte('-')
+			}
+			code.WriteByte(recoveryAlphabet[int(b)%len(recoveryAlphabet)])
+		}
+
+		codes = append(codes, code.String())
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code.String())))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func (s *authService) getUser(userID string) (*entity.UserCredentials, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.authRepository.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/adapters/lockout"
+	"airbnb-clone/auth/internal/adapters/oidc/oidctest"
+	"airbnb-clone/auth/internal/adapters/totp"
+	"airbnb-clone/auth/internal/config"
+	"airbnb-clone/auth/internal/domain/entity"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+const freeCodeAttempts = 3
+
+func newTestLimiter() *lockout.Limiter {
+	return lockout.NewLimiter(lockout.NewMemoryStore(), config.Lockout{
+		AccountFreeAttempts: freeCodeAttempts, AccountBaseDelay: time.Minute, AccountMaxDelay: time.Hour,
+		IPFreeAttempts: 100, IPBaseDelay: time.Minute, IPMaxDelay: time.Hour, ResetAfter: time.Hour,
+	})
+}
+
+func newLockoutTestService(t *testing.T, repo *fakeRepository) *authService {
+	t.Helper()
+
+	s := newTestService(t, repo)
+	s.limiter = newTestLimiter()
+	return s
+}
+
+// codes returns a currently valid code of the secret and one that is not
+func codes(t *testing.T, secret string) (string, string) {
+	t.Helper()
+
+	now := totp.Step(time.Now())
+	var valid []string
+	for step := now - 1; step <= now+1; step++ {
+		code, err := totp.Code(secret, step)
+		if err != nil {
+			t.Fatalf("totp code: %v", err)
+		}
+		valid = append(valid, code)
+	}
+
+	for _, wrong := range []string{"000000", "111111", "222222", "333333"} {
+		if !slices.Contains(valid, wrong) {
+			return valid[1], wrong
+		}
+	}
+	t.Fatal("no wrong code found")
+	return "", ""
+}
+
+func TestConfirmTOTPLocksOutCodeGuessing(t *testing.T) {
+	repo := newFakeRepository()
+	s := newLockoutTestService(t, repo)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("generate secret: %v", err)
+	}
+	user := repo.addUser("host@example.com")
+	repo.data.users[user.ID].TOTPSecret = secret
+	valid, wrong := codes(t, secret)
+	client := entity.ClientInfo{IP: "203.0.113.7"}
+
+	for range freeCodeAttempts + 1 {
+		if _, err := s.ConfirmTOTP(user.ID, wrong, client); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("wrong code: got %v, want %v", err, ErrInvalidMFACode)
+		}
+	}
+
+	var rateErr *RateLimitError
+	if _, err := s.ConfirmTOTP(user.ID, valid, client); !errors.As(err, &rateErr) {
+		t.Fatalf("code after lockout: got %v, want a *RateLimitError", err)
+	}
+}
+
+func TestDisableTOTPLocksOutCodeGuessing(t *testing.T) {
+	repo := newFakeRepository()
+	s := newLockoutTestService(t, repo)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("generate secret: %v", err)
+	}
+	user := repo.addUser("host@example.com")
+	repo.data.users[user.ID].TOTPSecret = secret
+	repo.data.users[user.ID].TOTPEnabled = true
+	valid, wrong := codes(t, secret)
+	client := entity.ClientInfo{IP: "203.0.113.7"}
+
+	for range freeCodeAttempts + 1 {
+		if err := s.DisableTOTP(user.ID, wrong, client); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("wrong code: got %v, want %v", err, ErrInvalidMFACode)
+		}
+	}
+
+	var rateErr *RateLimitError
+	if err := s.DisableTOTP(user.ID, valid, client); !errors.As(err, &rateErr) {
+		t.Fatalf("code after lockout: got %v, want a *RateLimitError", err)
+	}
+	if !repo.data.users[user.ID].TOTPEnabled {
+		t.Error("2FA was disabled during the lockout")
+	}
+}
+
+func TestCompleteMFALoginKeepsFirstFactor(t *testing.T) {
+	const password = "correct horse battery staple"
+
+	tests := []struct {
+		name  string
+		login func(t *testing.T, s *authService, issuer *oidctest.Issuer) error
+		want  []string
+	}{
+		{
+			name: "password",
+			login: func(t *testing.T, s *authService, _ *oidctest.Issuer) error {
+				_, err := s.LoginExistingUser("host@example.com", password, entity.ClientInfo{})
+				return err
+			},
+			want: []string{amrPassword, amrOTP, amrMFA},
+		},
+		{
+			name: "social login",
+			login: func(t *testing.T, s *authService, issuer *oidctest.Issuer) error {
+				_, err := oidcLogin(t, s, issuer, oidctest.User{Subject: "sub-1", Email: "host@example.com", EmailVerified: true})
+				return err
+			},
+			want: []string{amrFederated, amrOTP, amrMFA},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			s, issuer := newOIDCTestService(t, repo)
+			s.limiter = newTestLimiter()
+			s.mfaConfig = config.MFA{ChallengeTTL: time.Minute, MaxAttempts: 5}
+
+			secret, err := totp.GenerateSecret()
+			if err != nil {
+				t.Fatalf("generate secret: %v", err)
+			}
+			hashedPassword, err := hashPassword(password)
+			if err != nil {
+				t.Fatalf("hash password: %v", err)
+			}
+			user := repo.addUser("host@example.com")
+			repo.data.users[user.ID].Password = hashedPassword
+			repo.data.users[user.ID].TOTPSecret = secret
+			repo.data.users[user.ID].TOTPEnabled = true
+
+			var mfaErr *MFARequiredError
+			if err := tt.login(t, s, issuer); !errors.As(err, &mfaErr) {
+				t.Fatalf("login: got %v, want a *MFARequiredError", err)
+			}
+
+			valid, _ := codes(t, secret)
+			tokens, err := s.CompleteMFALogin(mfaErr.ChallengeToken, valid, entity.ClientInfo{})
+			if err != nil {
+				t.Fatalf("complete mfa login: %v", err)
+			}
+
+			claims, err := s.parseAccessToken(tokens.AccessToken)
+			if err != nil {
+				t.Fatalf("parse access token: %v", err)
+			}
+			if !slices.Equal(claims.AuthMethods, tt.want) {
+				t.Errorf("amr = %v, want %v", claims.AuthMethods, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/adapters/oidc"
+	"airbnb-clone/auth/internal/adapters/repository"
+	"airbnb-clone/auth/internal/domain/entity"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// oidcTimeout bounds the calls to the identity provider
+const oidcTimeout = 15 * time.Second
+
+// Return the URL of the provider the user is redirected to. Error can be
+// ErrUnknownProvider type
+func (s *authService) StartOIDCLogin(providerName string) (string, error) {
+	const fn = "domain.service.StartOIDCLogin"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	provider, err := s.oidcProviders.Provider(providerName)
+	if err != nil {
+		return "", ErrUnknownProvider
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Error("failed to build authorization url", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "provider", Value: slog.StringValue(providerName)})
+		return "", err
+	}
+
+	loginState := &entity.OIDCLoginState{
+		StateHash:    hashToken(state),
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(s.oidcConfig.StateTTL),
+	}
+	if err := s.authRepository.CreateOIDCLoginState(loginState); err != nil {
+		log.Error("failed to save login state", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+// Finish a social login and return our own token pair. The external identity
+// is linked to the user with the same email if both the provider and the user
+// verified it, and a new user is created if there is none. Users with 2FA get
+// a *MFARequiredError. Error can be ErrUnknownProvider, ErrInvalidOIDCState,
+// ErrOIDCFailed, ErrOIDCEmailNotVerified, ErrAccountNotVerified or
+// ErrIdentityConflict type
+func (s *authService) CompleteOIDCLogin(providerName string, code string, state string, client entity.ClientInfo) (*JWTTokenPair, error) {
+	const fn = "domain.service.CompleteOIDCLogin"
+	log := s.log.With(
+		slog.String("fn", fn),
+		slog.String("provider", providerName),
+	)
+
+	provider, err := s.oidcProviders.Provider(providerName)
+	if err != nil {
+		return &JWTTokenPair{}, ErrUnknownProvider
+	}
+
+	loginState, err := s.authRepository.TakeOIDCLoginState(hashToken(state))
+	if err != nil {
+		if errors.Is(err, repository.ErrOIDCStateNotFound) {
+			return &JWTTokenPair{}, ErrInvalidOIDCState
+		}
+		log.Error("failed to get login state", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return &JWTTokenPair{}, err
+	}
+	if loginState.Provider != provider.Name() || time.Now().After(loginState.ExpiresAt) {
+		return &JWTTokenPair{}, ErrInvalidOIDCState
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+
+	identity, err := provider.Exchange(ctx, code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		log.Error("failed to exchange authorization code", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return &JWTTokenPair{}, ErrOIDCFailed
+	}
+
+	user, err := s.userForIdentity(provider.Name(), identity)
+	if err != nil {
+		if errors.Is(err, ErrOIDCEmailNotVerified) || errors.Is(err, ErrAccountNotVerified) || errors.Is(err, ErrIdentityConflict) {
+			return &JWTTokenPair{}, err
+		}
+		log.Error("failed to link external identity", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return &JWTTokenPair{}, err
+	}
+
+	if user.TOTPEnabled {
+		return &JWTTokenPair{}, s.startMFAChallenge(user, federatedAuth)
+	}
+
+	jwtTokens, err := s.issueTokenPair(s.authRepository, user, "", federatedAuth, client)
+	if err != nil {
+		log.Error("failed to issue JWT tokens", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "UserUUID", Value: slog.StringValue(user.ID)})
+		return &JWTTokenPair{}, err
+	}
+
+	return jwtTokens, nil
+}
+
+// userForIdentity returns the user the identity is linked to, linking or
+// creating one on the first login
+func (s *authService) userForIdentity(provider string, identity *oidc.Identity) (*entity.UserCredentials, error) {
+	linked, err := s.authRepository.GetExternalIdentity(provider, identity.Subject)
+	if err == nil {
+		return s.authRepository.GetUserByID(linked.UserID)
+	}
+	if !errors.Is(err, repository.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	// linking by an unverified email would hand the account to whoever
+	// registered that address at the provider
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	var user *entity.UserCredentials
+	err = s.authRepository.WithinTransaction(func(repo repository.AuthRepository) error {
+		var err error
+		user, err = repo.GetUserByEmail(identity.Email)
+		switch {
+		case err == nil:
+			// anyone can register an address they do not own and wait for its
+			// owner to log in through the provider, so only accounts that
+			// proved they own the email are linked
+			if !user.EmailVerified {
+				return ErrAccountNotVerified
+			}
+		case errors.Is(err, repository.ErrEmailNotFound):
+			user, err = s.createFederatedUser(repo, identity.Email)
+			if err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return repo.CreateExternalIdentity(&entity.ExternalIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrIdentityExists) || errors.Is(err, repository.ErrEmailExist) {
+			return nil, ErrIdentityConflict
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createFederatedUser creates a user with a verified email and a random
+// password. They can set a password of their own with a reset
+func (s *authService) createFederatedUser(repo repository.AuthRepository, email string) (*entity.UserCredentials, error) {
+	password, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &entity.UserCredentials{Email: email, Password: hashedPassword, EmailVerified: true, EmailVerifiedAt: &now}
+	if _, err := repo.CreateNewUser(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.grantBootstrapAdmin(repo, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/adapters/oidc"
+	"airbnb-clone/auth/internal/adapters/oidc/oidctest"
+	"airbnb-clone/auth/internal/config"
+	"airbnb-clone/auth/internal/domain/entity"
+	"errors"
+	"testing"
+	"time"
+)
+
+const testProvider = "test"
+
+// newOIDCTestService returns a service with testProvider served by an
+// in-process issuer
+func newOIDCTestService(t *testing.T, repo *fakeRepository) (*authService, *oidctest.Issuer) {
+	t.Helper()
+
+	issuer, err := oidctest.NewIssuer()
+	if err != nil {
+		t.Fatalf("start issuer: %v", err)
+	}
+	t.Cleanup(issuer.Close)
+
+	s := newTestService(t, repo)
+	s.oidcConfig = config.OIDC{
+		StateTTL: time.Minute,
+		Providers: map[string]config.OIDCProvider{
+			testProvider: {Issuer: issuer.URL(), ClientID: "airbnb-clone", RedirectURL: "http://localhost:3000/oidc/callback"},
+		},
+	}
+	s.oidcProviders = oidc.NewRegistry(s.oidcConfig)
+
+	return s, issuer
+}
+
+// oidcLogin runs a social login of the user through the issuer
+func oidcLogin(t *testing.T, s *authService, issuer *oidctest.Issuer, user oidctest.User) (*JWTTokenPair, error) {
+	t.Helper()
+
+	authURL, err := s.StartOIDCLogin(testProvider)
+	if err != nil {
+		t.Fatalf("start login: %v", err)
+	}
+	code, state, err := issuer.Authorize(authURL, user)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+
+	return s.CompleteOIDCLogin(testProvider, code, state, entity.ClientInfo{})
+}
+
+// tokenUser returns the user the access token was issued to
+func tokenUser(t *testing.T, s *authService, tokens *JWTTokenPair) string {
+	t.Helper()
+
+	userID, err := s.ValidateAccessToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("validate access token: %v", err)
+	}
+	return userID
+}
+
+func TestCompleteOIDCLoginLinksVerifiedAccount(t *testing.T) {
+	repo := newFakeRepository()
+	s, issuer := newOIDCTestService(t, repo)
+	local := repo.addUser("guest@example.com")
+
+	tokens, err := oidcLogin(t, s, issuer, oidctest.User{Subject: "sub-1", Email: "guest@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	if got := tokenUser(t, s, tokens); got != local.ID {
+		t.Errorf("logged in as %s, want the local account %s", got, local.ID)
+	}
+	if len(repo.data.identities) != 1 || repo.data.identities[0].UserID != local.ID {
+		t.Errorf("identities = %+v, want one linked to %s", repo.data.identities, local.ID)
+	}
+}
+
+func TestCompleteOIDCLoginRefusesUnverifiedAccount(t *testing.T) {
+	repo := newFakeRepository()
+	s, issuer := newOIDCTestService(t, repo)
+	// registered by someone who never proved they own the address
+	local := repo.addUser("guest@example.com")
+	repo.data.users[local.ID].EmailVerified = false
+
+	_, err := oidcLogin(t, s, issuer, oidctest.User{Subject: "sub-1", Email: "guest@example.com", EmailVerified: true})
+	if !errors.Is(err, ErrAccountNotVerified) {
+		t.Fatalf("got %v, want %v", err, ErrAccountNotVerified)
+	}
+
+	if len(repo.data.identities) != 0 {
+		t.Errorf("identities = %+v, want none", repo.data.identities)
+	}
+	if repo.data.users[local.ID].EmailVerified {
+		t.Error("the local account was marked as verified")
+	}
+}
+
+func TestCompleteOIDCLoginCreatesUser(t *testing.T) {
+	repo := newFakeRepository()
+	s, issuer := newOIDCTestService(t, repo)
+
+	tokens, err := oidcLogin(t, s, issuer, oidctest.User{Subject: "sub-1", Email: "new@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	user, ok := repo.data.users[tokenUser(t, s, tokens)]
+	if !ok || user.Email != "new@example.com" || !user.EmailVerified {
+		t.Fatalf("user = %+v, want a new verified user", user)
+	}
+
+	// the second login finds the identity linked by the first
+	again, err := oidcLogin(t, s, issuer, oidctest.User{Subject: "sub-1", Email: "new@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if got := tokenUser(t, s, again); got != user.ID {
+		t.Errorf("second login as %s, want %s", got, user.ID)
+	}
+}
+
+func TestCompleteOIDCLoginRequiresVerifiedProviderEmail(t *testing.T) {
+	repo := newFakeRepository()
+	s, issuer := newOIDCTestService(t, repo)
+	repo.addUser("guest@example.com")
+
+	_, err := oidcLogin(t, s, issuer, oidctest.User{Subject: "sub-1", Email: "guest@example.com"})
+	if !errors.Is(err, ErrOIDCEmailNotVerified) {
+		t.Fatalf("got %v, want %v", err, ErrOIDCEmailNotVerified)
+	}
+	if len(repo.data.identities) != 0 {
+		t.Errorf("identities = %+v, want none", repo.data.identities)
+	}
+}
+
+func TestCompleteOIDCLoginChecksState(t *testing.T) {
+	repo := newFakeRepository()
+	s, issuer := newOIDCTestService(t, repo)
+	user := oidctest.User{Subject: "sub-1", Email: "guest@example.com", EmailVerified: true}
+
+	authURL, err := s.StartOIDCLogin(testProvider)
+	if err != nil {
+		t.Fatalf("start login: %v", err)
+	}
+	code, state, err := issuer.Authorize(authURL, user)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+
+	if _, err := s.CompleteOIDCLogin(testProvider, code, "forged-state", entity.ClientInfo{}); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("unknown state: got %v, want %v", err, ErrInvalidOIDCState)
+	}
+
+	if _, err := s.CompleteOIDCLogin(testProvider, code, state, entity.ClientInfo{}); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// the state is single use, a replayed callback is refused
+	code, _, err = issuer.Authorize(authURL, user)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if _, err := s.CompleteOIDCLogin(testProvider, code, state, entity.ClientInfo{}); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("replayed state: got %v, want %v", err, ErrInvalidOIDCState)
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/adapters/mailer"
+	"airbnb-clone/auth/internal/adapters/repository"
+	"airbnb-clone/auth/internal/domain/entity"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Mail a password reset link to the user. The result is the same whether or
+// not an account uses the email, so callers cannot probe for accounts. The mail
+// is sent in the background, otherwise the response time would tell
+func (s *authService) ForgotPassword(email string) error {
+	const fn = "domain.service.ForgotPassword"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	user, err := s.authRepository.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmailNotFound) {
+			return nil
+		}
+		log.Error("failed to get user by email", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
+	}
+
+	go s.mailPasswordReset(log, user)
+
+	return nil
+}
+
+// mailPasswordReset sends a reset link unless the user already got MaxEmails
+// of them within EmailWindow. Failures are only logged, reporting them would
+// tell that the account exists
+func (s *authService) mailPasswordReset(log *slog.Logger, user *entity.UserCredentials) {
+	log = log.With(slog.Attr{Key: "UserUUID", Value: slog.StringValue(user.ID)})
+
+	sent, err := s.authRepository.CountRecentPasswordResetTokens(user.ID, time.Now().Add(-s.resetConfig.EmailWindow))
+	if err != nil {
+		log.Error("failed to count reset tokens", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return
+	}
+	if sent >= int64(s.resetConfig.MaxEmails) {
+		log.Warn("password reset limit reached")
+		return
+	}
+
+	if err := s.sendPasswordResetEmail(user); err != nil {
+		log.Error("failed to send password reset email", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+	}
+}
+
+// Set a new password with a token from ForgotPassword, log the user out
+// everywhere and lift a login lockout. Error can be ErrInvalidResetToken type
+func (s *authService) ResetPassword(token string, newPassword string) error {
+	const fn = "domain.service.ResetPassword"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	reset, err := s.authRepository.GetPasswordResetToken(hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrResetTokenNotFound) {
+			return ErrInvalidResetToken
+		}
+		log.Error("failed to get reset token", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
+	}
+
+	if !reset.IsValid() {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := hashPassword(newPassword)
+	if err != nil {
+		log.Error("failed to hash password", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
+	}
+
+	err = s.authRepository.WithinTransaction(func(repo repository.AuthRepository) error {
+		if err := repo.UsePasswordResetToken(reset.ID); err != nil {
+			return err
+		}
+		if err := repo.InvalidatePasswordResetTokens(reset.UserID); err != nil {
+			return err
+		}
+		if err := repo.UpdatePassword(reset.UserID, hashedPassword); err != nil {
+			return err
+		}
+		// the reset link reached the inbox, which proves the address
+		if err := repo.MarkEmailVerified(reset.UserID); err != nil {
+			return err
+		}
+		return repo.RevokeAllUserTokens(reset.UserID)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrResetTokenUsed) || errors.Is(err, repository.ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+		log.Error("failed to reset password", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "UserUUID", Value: slog.StringValue(reset.UserID)})
+		return err
+	}
+
+	// a reset is the way out of a locked account
+	if user, err := s.authRepository.GetUserByID(reset.UserID); err == nil {
+		s.unlockLogin(log, user.Email)
+	}
+
+	return nil
+}
+
+// Replace the password of a logged in user. Every session is revoked and a new
+// token pair is returned for the current client. Wrong passwords count towards
+// the login lockout. Error can be ErrInvalidPassword or a *RateLimitError
+func (s *authService) ChangePassword(userID string, currentPassword string, newPassword string, client entity.ClientInfo) (*JWTTokenPair, error) {
+	const fn = "domain.service.ChangePassword"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	user, err := s.authRepository.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return &JWTTokenPair{}, ErrUserNotFound
+		}
+		log.Error("failed to get user", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return &JWTTokenPair{}, err
+	}
+	if err := s.checkCodeLockout(log, user.Email, client.IP); err != nil {
+		return &JWTTokenPair{}, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			s.recordLoginFailure(log, user.Email, client.IP)
+			return &JWTTokenPair{}, ErrInvalidPassword
+		}
+		log.Error("failed to compare password", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return &JWTTokenPair{}, err
+	}
+
+	hashedPassword, err := hashPassword(newPassword)
+	if err != nil {
+		log.Error("failed to hash password", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return &JWTTokenPair{}, err
+	}
+
+	var jwtTokens *JWTTokenPair
+	err = s.authRepository.WithinTransaction(func(repo repository.AuthRepository) error {
+		if err := repo.UpdatePassword(user.ID, hashedPassword); err != nil {
+			return err
+		}
+		if err := repo.InvalidatePasswordResetTokens(user.ID); err != nil {
+			return err
+		}
+		if err := repo.RevokeAllUserTokens(user.ID); err != nil {
+			return err
+		}
+
+		var err error
+		jwtTokens, err = s.issueTokenPair(repo, user, "", passwordAuth, client)
+		return err
+	})
+	if err != nil {
+		log.Error("failed to change password", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "UserUUID", Value: slog.StringValue(user.ID)})
+		return &JWTTokenPair{}, err
+	}
+
+	return jwtTokens, nil
+}
+
+func (s *authService) sendPasswordResetEmail(user *entity.UserCredentials) error {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(s.resetConfig.LinkURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	reset := &entity.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.resetConfig.TokenTTL),
+	}
+	if err := s.authRepository.CreatePasswordResetToken(reset); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account.\n\n"+
+			"Choose a new password by opening the link below:\n\n%s\n\n"+
+			"The link expires in %s and can be used once. If you did not ask for this, you can ignore this email.\n",
+			link.String(), s.resetConfig.TokenTTL),
+	})
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/adapters/mailer"
+	"airbnb-clone/auth/internal/config"
+	"airbnb-clone/auth/internal/domain/entity"
+	"errors"
+	"testing"
+	"time"
+)
+
+// slowMailer holds every message until release is closed
+type slowMailer struct {
+	fakeMailer
+	release chan struct{}
+}
+
+func (m *slowMailer) Send(msg mailer.Message) error {
+	<-m.release
+	return m.fakeMailer.Send(msg)
+}
+
+func TestForgotPasswordDoesNotWaitForTheMail(t *testing.T) {
+	repo := newFakeRepository()
+	s := newTestService(t, repo)
+	mails := &slowMailer{release: make(chan struct{})}
+	s.mailer = mails
+	s.resetConfig = config.PasswordReset{LinkURL: "http://localhost:3000/reset-password", TokenTTL: time.Hour,
+		MaxEmails: 3, EmailWindow: time.Hour}
+
+	user := repo.addUser("host@example.com")
+
+	for _, email := range []string{"nobody@example.com", user.Email} {
+		done := make(chan error, 1)
+		go func() { done <- s.ForgotPassword(email) }()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("forgot password for %s: %v", email, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("forgot password for %s waited for the mail", email)
+		}
+	}
+
+	close(mails.release)
+	deadline := time.Now().Add(time.Second)
+	for len(mails.messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	sent := mails.messages()
+	if len(sent) != 1 || sent[0].To != user.Email {
+		t.Fatalf("sent %v, want one mail to %s", sent, user.Email)
+	}
+}
+
+func TestChangePasswordLocksOutPasswordGuessing(t *testing.T) {
+	repo := newFakeRepository()
+	s := newLockoutTestService(t, repo)
+
+	user := repo.addUser("host@example.com")
+	hashed, err := hashPassword("current password")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	repo.data.users[user.ID].Password = hashed
+	client := entity.ClientInfo{IP: "203.0.113.7"}
+
+	for range freeCodeAttempts + 1 {
+		if _, err := s.ChangePassword(user.ID, "wrong password", "new password", client); !errors.Is(err, ErrInvalidPassword) {
+			t.Fatalf("wrong password: got %v, want %v", err, ErrInvalidPassword)
+		}
+	}
+
+	var rateErr *RateLimitError
+	if _, err := s.ChangePassword(user.ID, "current password", "new password", client); !errors.As(err, &rateErr) {
+		t.Fatalf("password after lockout: got %v, want a *RateLimitError", err)
+	}
+	if repo.data.users[user.ID].Password != hashed {
+		t.Error("the password was changed during the lockout")
+	}
+}
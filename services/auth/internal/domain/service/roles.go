@@ -0,0 +1,160 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/adapters/repository"
+	"airbnb-clone/auth/internal/domain/entity"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Return the roles of a user, including the implicit guest role. Error can be
+// ErrForbidden or ErrUserNotFound type
+func (s *authService) GetUserRoles(adminID string, userID string) ([]string, error) {
+	const fn = "domain.service.GetUserRoles"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	if err := s.requirePermission(adminID, entity.PermissionManageRoles); err != nil {
+		return nil, err
+	}
+	if _, err := s.getUser(userID); err != nil {
+		return nil, err
+	}
+
+	roles, err := userRoles(s.authRepository, userID)
+	if err != nil {
+		log.Error("failed to get user roles", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// Grant a role to a user. It shows up in their tokens after the next refresh.
+// Error can be ErrForbidden, ErrUserNotFound or ErrInvalidRole type
+func (s *authService) GrantRole(adminID string, userID string, role string) error {
+	const fn = "domain.service.GrantRole"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	if err := s.requirePermission(adminID, entity.PermissionManageRoles); err != nil {
+		return err
+	}
+	if !entity.IsValidRole(role) || role == entity.RoleGuest {
+		return ErrInvalidRole
+	}
+	if _, err := s.getUser(userID); err != nil {
+		return err
+	}
+
+	if err := s.authRepository.GrantRole(&entity.UserRole{UserID: userID, Role: role, GrantedBy: adminID}); err != nil {
+		log.Error("failed to grant role", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
+	}
+
+	log.Info("role granted", slog.String("admin_id", adminID), slog.String("user_id", userID), slog.String("role", role))
+	return nil
+}
+
+// Revoke a role from a user. Admins cannot revoke their own admin role, so
+// there is always one left. Error can be ErrForbidden, ErrInvalidRole or
+// ErrRoleNotFound type
+func (s *authService) RevokeRole(adminID string, userID string, role string) error {
+	const fn = "domain.service.RevokeRole"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	if err := s.requirePermission(adminID, entity.PermissionManageRoles); err != nil {
+		return err
+	}
+	if !entity.IsValidRole(role) || role == entity.RoleGuest {
+		return ErrInvalidRole
+	}
+	if adminID == userID && role == entity.RoleAdmin {
+		return ErrForbidden
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		return ErrRoleNotFound
+	}
+
+	if err := s.authRepository.RevokeRole(userID, role); err != nil {
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			return ErrRoleNotFound
+		}
+		log.Error("failed to revoke role", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
+	}
+
+	log.Info("role revoked", slog.String("admin_id", adminID), slog.String("user_id", userID), slog.String("role", role))
+	return nil
+}
+
+// Grant the admin role to the configured bootstrap admin if they registered
+// and verified their email
+func (s *authService) BootstrapAdmin() error {
+	const fn = "domain.service.BootstrapAdmin"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	if s.rbacConfig.BootstrapAdmin == "" {
+		return nil
+	}
+
+	user, err := s.authRepository.GetUserByEmail(s.rbacConfig.BootstrapAdmin)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmailNotFound) {
+			log.Info("bootstrap admin has not registered yet")
+			return nil
+		}
+		return err
+	}
+	if !user.EmailVerified {
+		log.Warn("bootstrap admin has not verified their email yet")
+		return nil
+	}
+
+	return s.grantBootstrapAdmin(s.authRepository, user)
+}
+
+func (s *authService) requirePermission(userID string, permission string) error {
+	roles, err := userRoles(s.authRepository, userID)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(entity.PermissionsOf(roles), permission) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// grantBootstrapAdmin grants the admin role to the user if they are the
+// configured bootstrap admin. Anyone can register an address, so the role is
+// only granted once it is verified
+func (s *authService) grantBootstrapAdmin(repo repository.AuthRepository, user *entity.UserCredentials) error {
+	if !user.EmailVerified || !s.isBootstrapAdmin(user.Email) {
+		return nil
+	}
+	return repo.GrantRole(&entity.UserRole{UserID: user.ID, Role: entity.RoleAdmin})
+}
+
+func (s *authService) isBootstrapAdmin(email string) bool {
+	return s.rbacConfig.BootstrapAdmin != "" && strings.EqualFold(email, s.rbacConfig.BootstrapAdmin)
+}
+
+// userRoles returns the stored roles of the user plus the implicit guest role
+func userRoles(repo repository.AuthRepository, userID string) ([]string, error) {
+	roles, err := repo.GetUserRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{entity.RoleGuest}, roles...), nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/config"
+	"airbnb-clone/auth/internal/domain/entity"
+	"net/url"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+const bootstrapAdmin = "admin@example.com"
+
+var linkPattern = regexp.MustCompile(`https?://\S+`)
+
+func newRBACTestService(t *testing.T, repo *fakeRepository) (*authService, *fakeMailer) {
+	t.Helper()
+
+	mail := &fakeMailer{}
+	s := newTestService(t, repo)
+	s.mailer = mail
+	s.rbacConfig = config.RBAC{BootstrapAdmin: bootstrapAdmin}
+	s.verifyConfig = config.Verification{LinkURL: "http://localhost:3000/verify-email", TokenTTL: time.Hour}
+	return s, mail
+}
+
+// mailedToken returns the token of the link in the last mail sent
+func mailedToken(t *testing.T, mail *fakeMailer) string {
+	t.Helper()
+
+	sent := mail.messages()
+	if len(sent) == 0 {
+		t.Fatal("no mail sent")
+	}
+	link, err := url.Parse(linkPattern.FindString(sent[len(sent)-1].Body))
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	return link.Query().Get("token")
+}
+
+func isAdmin(t *testing.T, repo *fakeRepository, userID string) bool {
+	t.Helper()
+
+	roles, err := repo.GetUserRoles(userID)
+	if err != nil {
+		t.Fatalf("get roles: %v", err)
+	}
+	return slices.Contains(roles, entity.RoleAdmin)
+}
+
+func TestBootstrapAdminIsGrantedOnVerification(t *testing.T) {
+	repo := newFakeRepository()
+	s, mail := newRBACTestService(t, repo)
+
+	if _, err := s.RegisterNewUser(bootstrapAdmin, "correct horse battery staple", entity.ClientInfo{}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	user, err := repo.GetUserByEmail(bootstrapAdmin)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+
+	if isAdmin(t, repo, user.ID) {
+		t.Fatal("admin role granted on registration, before the email was verified")
+	}
+	if err := s.BootstrapAdmin(); err != nil {
+		t.Fatalf("bootstrap admin: %v", err)
+	}
+	if isAdmin(t, repo, user.ID) {
+		t.Fatal("admin role granted on startup to an unverified user")
+	}
+
+	if err := s.VerifyEmail(mailedToken(t, mail)); err != nil {
+		t.Fatalf("verify email: %v", err)
+	}
+	if !isAdmin(t, repo, user.ID) {
+		t.Error("admin role not granted after the email was verified")
+	}
+}
+
+func TestBootstrapAdminOnStartup(t *testing.T) {
+	repo := newFakeRepository()
+	s, _ := newRBACTestService(t, repo)
+	user := repo.addUser(bootstrapAdmin)
+
+	if err := s.BootstrapAdmin(); err != nil {
+		t.Fatalf("bootstrap admin: %v", err)
+	}
+	if !isAdmin(t, repo, user.ID) {
+		t.Error("admin role not granted to the verified bootstrap admin")
+	}
+}
@@ -1,27 +1,69 @@
 package service
 
 import (
+	"airbnb-clone/auth/internal/adapters/keys"
+	"airbnb-clone/auth/internal/adapters/lockout"
+	"airbnb-clone/auth/internal/adapters/mailer"
+	"airbnb-clone/auth/internal/adapters/oidc"
 	"airbnb-clone/auth/internal/adapters/repository"
+	"airbnb-clone/auth/internal/config"
 	"airbnb-clone/auth/internal/domain/entity"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"log/slog"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService interface {
-	RegisterNewUser(email string, password string) (*JWTTokenPair, error)
-	LoginExistingUser(email string, password string) (*JWTTokenPair, error)
-	ValidateRefreshToken(refreshToken string) (string, error)
+	RegisterNewUser(email string, password string, client entity.ClientInfo) (*JWTTokenPair, error)
+	LoginExistingUser(email string, password string, client entity.ClientInfo) (*JWTTokenPair, error)
+	RefreshTokens(refreshToken string, client entity.ClientInfo) (*JWTTokenPair, error)
+	ValidateAccessToken(accessToken string) (string, error)
+	JWKS() keys.JWKSet
+	Logout(refreshToken string) error
+	LogoutAll(userID string) error
+	GetSessions(userID string) ([]entity.Session, error)
+	RevokeSession(userID string, sessionID string) error
+	VerifyEmail(token string) error
+	ResendVerificationEmail(userID string) error
+	ForgotPassword(email string) error
+	ResetPassword(token string, newPassword string) error
+	ChangePassword(userID string, currentPassword string, newPassword string, client entity.ClientInfo) (*JWTTokenPair, error)
+	EnrollTOTP(userID string) (*TOTPEnrollment, error)
+	ConfirmTOTP(userID string, code string, client entity.ClientInfo) ([]string, error)
+	DisableTOTP(userID string, code string, client entity.ClientInfo) error
+	CompleteMFALogin(challengeToken string, code string, client entity.ClientInfo) (*JWTTokenPair, error)
+	GetUserRoles(adminID string, userID string) ([]string, error)
+	GrantRole(adminID string, userID string, role string) error
+	RevokeRole(adminID string, userID string, role string) error
+	BootstrapAdmin() error
+	StartOIDCLogin(provider string) (string, error)
+	CompleteOIDCLogin(provider string, code string, state string, client entity.ClientInfo) (*JWTTokenPair, error)
+	IntrospectToken(accessToken string) (*TokenIntrospection, error)
+	GetUser(userID string) (*UserInfo, error)
+	BatchGetUsers(userIDs []string) ([]UserInfo, []string, error)
 }
 
 type authService struct {
 	authRepository repository.AuthRepository
+	keys           *keys.Manager
+	jwtConfig      config.JWT
+	tokenParser    *jwt.Parser
+	mailer         mailer.Mailer
+	verifyConfig   config.Verification
+	resetConfig    config.PasswordReset
+	mfaConfig      config.MFA
+	limiter        *lockout.Limiter
+	rbacConfig     config.RBAC
+	oidcProviders  *oidc.Registry
+	oidcConfig     config.OIDC
 	log            *slog.Logger
 }
 
@@ -32,12 +74,16 @@ type JWTTokenPair struct {
 	RefreshExprireTime time.Time
 }
 
-func NewAuthService(authRepo repository.AuthRepository, logger *slog.Logger) AuthService {
-	return &authService{authRepository: authRepo, log: logger}
+func NewAuthService(authRepo repository.AuthRepository, keyManager *keys.Manager, mailSender mailer.Mailer, limiter *lockout.Limiter,
+	oidcProviders *oidc.Registry, cfg *config.Config, logger *slog.Logger) AuthService {
+	return &authService{authRepository: authRepo, keys: keyManager, jwtConfig: cfg.JWT, tokenParser: newTokenParser(),
+		mailer: mailSender, verifyConfig: cfg.Verification, resetConfig: cfg.PasswordReset,
+		mfaConfig: cfg.MFA, limiter: limiter, rbacConfig: cfg.RBAC,
+		oidcProviders: oidcProviders, oidcConfig: cfg.OIDC, log: logger}
 }
 
 // Return generated access and refresh tokens or error
-func (s *authService) RegisterNewUser(email string, password string) (*JWTTokenPair, error) {
+func (s *authService) RegisterNewUser(email string, password string, client entity.ClientInfo) (*JWTTokenPair, error) {
 	const fn = "domain.service.RegisterNewUser"
 	log := s.log.With(
 		slog.String("fn", fn),
@@ -60,35 +106,53 @@ func (s *authService) RegisterNewUser(email string, password string) (*JWTTokenP
 		return &JWTTokenPair{}, err
 	}
 
-	jwtTokens, err := generateJWTTokenPair(uuid)
-	if err != nil {
-		log.Error("failed to create JWT tokens", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+	// the account stays usable if the mail cannot be sent, the user can ask
+	// for another one
+	if err := s.sendVerificationEmail(newUser); err != nil {
+		log.Error("failed to send verification email", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
 			slog.Attr{Key: "UserUUID", Value: slog.StringValue(uuid)})
-		return &JWTTokenPair{}, err
 	}
 
-	refreshToken := &entity.RefreshToken{TokenHash: jwtTokens.RefreshToken, UserID: uuid, ExpiresAt: jwtTokens.RefreshExprireTime}
-	refreshToken.HashToken(jwtTokens.RefreshToken) // hashing the token to store in database
-	if err = s.authRepository.CreateRefreshToken(refreshToken); err != nil {
-		log.Error("failed to store refresh token in database", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+	jwtTokens, err := s.issueTokenPair(s.authRepository, newUser, "", passwordAuth, client)
+	if err != nil {
+		log.Error("failed to issue JWT tokens", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "UserUUID", Value: slog.StringValue(uuid)})
 		return &JWTTokenPair{}, err
 	}
 
 	return jwtTokens, nil
 }
 
-// Return  generated access and refresh tokens or error. Error can be ErrEmailNotFound type
-func (s *authService) LoginExistingUser(email string, password string) (*JWTTokenPair, error) {
+// Return generated access and refresh tokens or error. A wrong email and a
+// wrong password both give ErrInvalidCredentials; repeated failures lock the
+// account and the client IP with a *RateLimitError. Users with 2FA get a
+// *MFARequiredError instead of tokens
+func (s *authService) LoginExistingUser(email string, password string, client entity.ClientInfo) (*JWTTokenPair, error) {
 	const fn = "domain.service.LoginExistingUser"
 	log := s.log.With(
 		slog.String("fn", fn),
 	)
 
+	retryAfter, err := s.limiter.RetryAfter(email, client.IP, time.Now())
+	if err != nil {
+		log.Error("failed to check login lockout", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return &JWTTokenPair{}, err
+	}
+	if retryAfter > 0 {
+		log.Warn("login locked", slog.Attr{Key: "email", Value: slog.StringValue(email)},
+			slog.Attr{Key: "ip", Value: slog.StringValue(client.IP)})
+		return &JWTTokenPair{}, &RateLimitError{RetryAfter: retryAfter}
+	}
+
 	user, err := s.authRepository.GetUserByEmail(email)
 	if err != nil {
 		if errors.Is(err, repository.ErrEmailNotFound) {
+			// compare anyway so that the response time does not tell whether
+			// the account exists
+			_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
 			log.Error("user with provided email not found", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
-			return &JWTTokenPair{}, ErrEmailNotFound
+			s.recordLoginFailure(log, email, client.IP)
+			return &JWTTokenPair{}, ErrInvalidCredentials
 		}
 		log.Error("failed to get user by email", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
 		return &JWTTokenPair{}, err
@@ -98,32 +162,34 @@ func (s *authService) LoginExistingUser(email string, password string) (*JWTToke
 	if err != nil {
 		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
 			log.Error("invalid password", slog.Attr{Key: "email", Value: slog.StringValue(email)})
-			return &JWTTokenPair{}, ErrInvalidPassword
+			s.recordLoginFailure(log, email, client.IP)
+			return &JWTTokenPair{}, ErrInvalidCredentials
 		}
 		log.Error("failed to compare password", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
 		return &JWTTokenPair{}, err
 	}
 
-	jwtTokens, err := generateJWTTokenPair(user.ID)
-	if err != nil {
-		log.Error("failed to create JWT tokens", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
-			slog.Attr{Key: "UserUUID", Value: slog.StringValue(user.ID)})
-		return &JWTTokenPair{}, err
+	// the account stays throttled until the second factor is checked too
+	if user.TOTPEnabled {
+		return &JWTTokenPair{}, s.startMFAChallenge(user, passwordAuth)
 	}
 
-	refreshToken := &entity.RefreshToken{TokenHash: jwtTokens.RefreshToken, UserID: user.ID, ExpiresAt: jwtTokens.RefreshExprireTime}
-	refreshToken.HashToken(jwtTokens.RefreshToken) // hashing the token to store in database
-	if err = s.authRepository.CreateRefreshToken(refreshToken); err != nil {
-		log.Error("failed to store refresh token in database", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+	jwtTokens, err := s.issueTokenPair(s.authRepository, user, "", passwordAuth, client)
+	if err != nil {
+		log.Error("failed to issue JWT tokens", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "UserUUID", Value: slog.StringValue(user.ID)})
 		return &JWTTokenPair{}, err
 	}
 
+	s.unlockLogin(log, user.Email)
 	return jwtTokens, nil
 }
 
-// Return newly generated access token or error
-func (s *authService) ValidateRefreshToken(refreshToken string) (string, error) {
-	const fn = "domain.service.ValidateRefreshToken"
+// Return a new token pair in exchange for a live refresh token. The presented
+// token is consumed: presenting it again is treated as theft and revokes every
+// token of its family, so both the attacker and the victim have to log in again
+func (s *authService) RefreshTokens(refreshToken string, client entity.ClientInfo) (*JWTTokenPair, error) {
+	const fn = "domain.service.RefreshTokens"
 	log := s.log.With(
 		slog.String("fn", fn),
 	)
@@ -133,54 +199,260 @@ func (s *authService) ValidateRefreshToken(refreshToken string) (string, error)
 	if err != nil {
 		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
 			log.Error("provided token not found", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
-			return "", ErrRefreshTokenNotFound
+			return &JWTTokenPair{}, ErrRefreshTokenNotFound
 		}
 		log.Error("failed to get a refresh token from database", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
-		return "", err
+		return &JWTTokenPair{}, err
+	}
+
+	if refresh.IsRevoked() {
+		return &JWTTokenPair{}, ErrRefreshTokenRevoked
+	}
+
+	if refresh.IsConsumed() {
+		s.revokeFamily(log, &refresh)
+		return &JWTTokenPair{}, ErrRefreshTokenReused
 	}
 
 	if !refresh.IsValid() { // check if the token is expired or not
-		return "", ErrRefreshTokenExpired
+		return &JWTTokenPair{}, ErrRefreshTokenExpired
 	}
 
-	jwtTokens, err := generateJWTTokenPair(refresh.UserID)
+	// the user is loaded again so that the new access token reflects changes
+	// such as a verified email
+	user, err := s.authRepository.GetUserByID(refresh.UserID)
 	if err != nil {
-		log.Error("failed to create JWT tokens", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+		log.Error("failed to get the token owner", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
 			slog.Attr{Key: "UserUUID", Value: slog.StringValue(refresh.UserID)})
+		return &JWTTokenPair{}, err
+	}
+
+	var jwtTokens *JWTTokenPair
+	err = s.authRepository.WithinTransaction(func(repo repository.AuthRepository) error {
+		if err := repo.ConsumeRefreshToken(refresh.ID); err != nil {
+			return err
+		}
+
+		var err error
+		jwtTokens, err = s.issueTokenPair(repo, user, refresh.FamilyID, strings.Fields(refresh.AuthMethods), client)
+		return err
+	})
+	if err != nil {
+		// a concurrent request consumed the token first
+		if errors.Is(err, repository.ErrRefreshTokenConsumed) {
+			s.revokeFamily(log, &refresh)
+			return &JWTTokenPair{}, ErrRefreshTokenReused
+		}
+		log.Error("failed to rotate refresh token", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "UserUUID", Value: slog.StringValue(refresh.UserID)})
+		return &JWTTokenPair{}, err
+	}
+
+	return jwtTokens, nil
+}
+
+// Return the user the access token was issued to or error
+func (s *authService) ValidateAccessToken(accessToken string) (string, error) {
+	claims, err := s.parseAccessToken(accessToken)
+	if err != nil {
 		return "", err
 	}
 
-	return jwtTokens.AccessToken, nil
+	return claims.UserID, nil
+}
+
+// parseAccessToken returns the claims of a validly signed, unexpired access
+// token. Error can be ErrInvalidToken type
+func (s *authService) parseAccessToken(accessToken string) (*tokenClaims, error) {
+	var claims tokenClaims
+	_, err := s.tokenParser.ParseWithClaims(accessToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		publicKey, ok := s.keys.PublicKey(kid)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return publicKey, nil
+	})
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if err := s.validateClaims(&claims, tokenTypeAccess, time.Now()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return &claims, nil
+}
+
+// Return the public keys tokens are verified with
+func (s *authService) JWKS() keys.JWKSet {
+	return s.keys.JWKS()
 }
 
-func generateJWTTokenPair(userUUID string) (*JWTTokenPair, error) {
-	var (
-		jwtSecret  = os.Getenv("JWT_SECRET")
-		accessTTL  = 15 * time.Minute
-		refreshTTL = 7 * 24 * time.Hour
+// Revoke the session the refresh token belongs to
+func (s *authService) Logout(refreshToken string) error {
+	const fn = "domain.service.Logout"
+	log := s.log.With(
+		slog.String("fn", fn),
 	)
 
-	accessExpire := time.Now().Add(accessTTL)
-	refreshExpire := time.Now().Add(refreshTTL)
+	refresh, err := s.authRepository.ValidateRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return ErrRefreshTokenNotFound
+		}
+		log.Error("failed to get a refresh token from database", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
+	}
 
-	accessClaims := jwt.MapClaims{
-		"user_id": userUUID,
-		"exp":     accessExpire.Unix(),
+	if err := s.authRepository.RevokeTokenFamily(refresh.FamilyID); err != nil {
+		log.Error("failed to revoke session", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
 	}
 
-	refreshClaims := jwt.MapClaims{
-		"user_id": userUUID,
-		"exp":     refreshExpire.Unix(),
+	return nil
+}
+
+// Revoke every session of the user
+func (s *authService) LogoutAll(userID string) error {
+	const fn = "domain.service.LogoutAll"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	if err := s.authRepository.RevokeAllUserTokens(userID); err != nil {
+		log.Error("failed to revoke user sessions", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(jwtSecret))
+	return nil
+}
+
+func (s *authService) GetSessions(userID string) ([]entity.Session, error) {
+	const fn = "domain.service.GetSessions"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	sessions, err := s.authRepository.GetActiveSessions(userID)
+	if err != nil {
+		log.Error("failed to get user sessions", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Revoke one session of the user. Error can be ErrSessionNotFound type
+func (s *authService) RevokeSession(userID string, sessionID string) error {
+	const fn = "domain.service.RevokeSession"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+
+	if err := s.authRepository.RevokeUserSession(userID, sessionID); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return ErrSessionNotFound
+		}
+		log.Error("failed to revoke session", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
+	}
+
+	return nil
+}
+
+func (s *authService) recordLoginFailure(log *slog.Logger, email string, ip string) {
+	if err := s.limiter.RecordFailure(email, ip, time.Now()); err != nil {
+		log.Error("failed to record login failure", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+	}
+}
+
+func (s *authService) unlockLogin(log *slog.Logger, email string) {
+	if err := s.limiter.Unlock(email); err != nil {
+		log.Error("failed to reset login failures", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+	}
+}
+
+func (s *authService) revokeFamily(log *slog.Logger, refresh *entity.RefreshToken) {
+	log.Warn("refresh token reuse detected, revoking token family",
+		slog.String("user_id", refresh.UserID), slog.String("family_id", refresh.FamilyID))
+
+	if err := s.authRepository.RevokeTokenFamily(refresh.FamilyID); err != nil {
+		log.Error("failed to revoke token family", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+	}
+}
+
+// issueTokenPair generates a token pair and stores the hash of its refresh
+// token. An empty familyID starts a new family. authMethods are the amr values
+// of the login and are kept across rotations
+func (s *authService) issueTokenPair(repo repository.AuthRepository, user *entity.UserCredentials, familyID string,
+	authMethods []string, client entity.ClientInfo) (*JWTTokenPair, error) {
+	roles, err := userRoles(repo, user.ID)
+	if err != nil {
+		return &JWTTokenPair{}, err
+	}
+
+	if familyID == "" {
+		familyID = uuid.New().String()
+	}
+
+	jwtTokens, err := s.generateJWTTokenPair(user, familyID, authMethods, roles)
+	if err != nil {
+		return &JWTTokenPair{}, err
+	}
+
+	refreshToken := &entity.RefreshToken{
+		UserID:      user.ID,
+		FamilyID:    familyID,
+		AuthMethods: strings.Join(authMethods, " "),
+		UserAgent:   truncate(client.UserAgent, 512),
+		IP:          truncate(client.IP, 64),
+		ExpiresAt:   jwtTokens.RefreshExprireTime,
+	}
+	refreshToken.HashToken(jwtTokens.RefreshToken) // hashing the token to store in database
+	if err = repo.CreateRefreshToken(refreshToken); err != nil {
+		return &JWTTokenPair{}, err
+	}
+
+	return jwtTokens, nil
+}
+
+func (s *authService) generateJWTTokenPair(user *entity.UserCredentials, familyID string, authMethods []string,
+	roles []string) (*JWTTokenPair, error) {
+	kid, signingKey, err := s.keys.SigningKey()
 	if err != nil {
 		return &JWTTokenPair{}, err
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(jwtSecret))
+	now := time.Now()
+	accessExpire := now.Add(s.jwtConfig.AccessTTL)
+	refreshExpire := now.Add(s.jwtConfig.RefreshTTL)
+
+	accessClaims := s.newClaims(user.ID, tokenTypeAccess, now, accessExpire)
+	accessClaims.EmailVerified = user.EmailVerified
+	accessClaims.AuthMethods = authMethods
+	accessClaims.Roles = roles
+	accessClaims.Permissions = entity.PermissionsOf(roles)
+	accessClaims.SessionID = familyID
+
+	// jti keeps refresh tokens unique even when issued within the same second
+	refreshClaims := s.newClaims(user.ID, tokenTypeRefresh, now, refreshExpire)
+	refreshClaims.Id = uuid.New().String()
+
+	accessToken := jwt.NewWithClaims(jwt.SigningMethodRS256, accessClaims)
+	accessToken.Header["kid"] = kid
+	accessTokenString, err := accessToken.SignedString(signingKey)
+	if err != nil {
+		return &JWTTokenPair{}, err
+	}
+
+	refreshToken := jwt.NewWithClaims(jwt.SigningMethodRS256, refreshClaims)
+	refreshToken.Header["kid"] = kid
+	refreshTokenString, err := refreshToken.SignedString(signingKey)
 	if err != nil {
 		return &JWTTokenPair{}, err
 	}
@@ -189,6 +461,16 @@ func generateJWTTokenPair(userUUID string) (*JWTTokenPair, error) {
 		RefreshExprireTime: refreshExpire, AccessExpireTime: accessExpire}, nil
 }
 
+func truncate(value string, size int) string {
+	if len(value) > size {
+		return value[:size]
+	}
+	return value
+}
+
+// dummyPasswordHash is compared against when the email is unknown
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost) // bcrypt.DefaultCost is a good starting point
 	return string(bytes), err
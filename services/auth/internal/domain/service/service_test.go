@@ -0,0 +1,84 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/domain/entity"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// login starts a token family for the user the way a password login does
+func login(t *testing.T, s *authService, user *entity.UserCredentials) *JWTTokenPair {
+	t.Helper()
+
+	tokens, err := s.issueTokenPair(s.authRepository, user, "", passwordAuth, entity.ClientInfo{})
+	if err != nil {
+		t.Fatalf("issue token pair: %v", err)
+	}
+	return tokens
+}
+
+// assertFamilyRevoked checks that no token of the family is left usable
+func assertFamilyRevoked(t *testing.T, repo *fakeRepository) {
+	t.Helper()
+
+	for _, token := range repo.data.refreshTokens {
+		if !token.IsRevoked() {
+			t.Errorf("refresh token %s of family %s is not revoked", token.ID, token.FamilyID)
+		}
+	}
+}
+
+func TestRefreshTokensReplayRevokesFamily(t *testing.T) {
+	repo := newFakeRepository()
+	s := newTestService(t, repo)
+	first := login(t, s, repo.addUser("guest@example.com"))
+
+	rotated, err := s.RefreshTokens(first.RefreshToken, entity.ClientInfo{})
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	if _, err := s.RefreshTokens(first.RefreshToken, entity.ClientInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replaying a rotated token: got %v, want %v", err, ErrRefreshTokenReused)
+	}
+	assertFamilyRevoked(t, repo)
+
+	if _, err := s.RefreshTokens(rotated.RefreshToken, entity.ClientInfo{}); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Fatalf("refreshing after reuse: got %v, want %v", err, ErrRefreshTokenRevoked)
+	}
+}
+
+func TestRefreshTokensConcurrentRefreshRevokesFamily(t *testing.T) {
+	repo := newFakeRepository()
+	s := newTestService(t, repo)
+	first := login(t, s, repo.addUser("guest@example.com"))
+
+	const clients = 2
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, clients)
+	)
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = s.RefreshTokens(first.RefreshToken, entity.ClientInfo{})
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrRefreshTokenReused):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d refreshes succeeded, want exactly 1", succeeded)
+	}
+	assertFamilyRevoked(t, repo)
+}
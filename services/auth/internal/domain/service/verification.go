@@ -0,0 +1,162 @@
+package service
+
+import (
+	"airbnb-clone/auth/internal/adapters/mailer"
+	"airbnb-clone/auth/internal/adapters/repository"
+	"airbnb-clone/auth/internal/domain/entity"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+// Mark the email of the token owner as verified. The token can only be used
+// once. The bootstrap admin gets the admin role here, once they proved they
+// own the address. Error can be ErrInvalidVerification type
+func (s *authService) VerifyEmail(token string) error {
+	const fn = "domain.service.VerifyEmail"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	verification, err := s.authRepository.GetEmailVerificationToken(hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrVerificationNotFound) {
+			return ErrInvalidVerification
+		}
+		log.Error("failed to get verification token", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
+	}
+
+	if !verification.IsValid() {
+		return ErrInvalidVerification
+	}
+
+	err = s.authRepository.WithinTransaction(func(repo repository.AuthRepository) error {
+		if err := repo.UseEmailVerificationToken(verification.ID); err != nil {
+			return err
+		}
+		if err := repo.MarkEmailVerified(verification.UserID); err != nil {
+			return err
+		}
+
+		user, err := repo.GetUserByID(verification.UserID)
+		if err != nil {
+			return err
+		}
+		return s.grantBootstrapAdmin(repo, user)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrVerificationUsed) {
+			return ErrInvalidVerification
+		}
+		log.Error("failed to verify email", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "UserUUID", Value: slog.StringValue(verification.UserID)})
+		return err
+	}
+
+	return nil
+}
+
+// Send a new verification email. Error can be ErrEmailAlreadyVerified or a
+// *RateLimitError
+func (s *authService) ResendVerificationEmail(userID string) error {
+	const fn = "domain.service.ResendVerificationEmail"
+	log := s.log.With(
+		slog.String("fn", fn),
+	)
+
+	user, err := s.authRepository.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		log.Error("failed to get user", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		return err
+	}
+
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	if err := s.checkVerificationRate(userID, time.Now()); err != nil {
+		return err
+	}
+
+	if err := s.sendVerificationEmail(user); err != nil {
+		log.Error("failed to send verification email", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())},
+			slog.Attr{Key: "UserUUID", Value: slog.StringValue(userID)})
+		return err
+	}
+
+	return nil
+}
+
+// checkVerificationRate enforces the resend cooldown and the number of emails
+// per window, counting the email sent on registration
+func (s *authService) checkVerificationRate(userID string, now time.Time) error {
+	cfg := s.verifyConfig
+
+	recent, err := s.authRepository.GetRecentEmailVerificationTokens(userID, now.Add(-cfg.EmailWindow))
+	if err != nil {
+		return err
+	}
+	if len(recent) == 0 {
+		return nil
+	}
+
+	if len(recent) >= cfg.MaxEmails {
+		oldest := recent[len(recent)-1]
+		return &RateLimitError{RetryAfter: oldest.CreatedAt.Add(cfg.EmailWindow).Sub(now)}
+	}
+
+	if next := recent[0].CreatedAt.Add(cfg.ResendCooldown); now.Before(next) {
+		return &RateLimitError{RetryAfter: next.Sub(now)}
+	}
+
+	return nil
+}
+
+func (s *authService) sendVerificationEmail(user *entity.UserCredentials) error {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(s.verifyConfig.LinkURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	verification := &entity.EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.verifyConfig.TokenTTL),
+	}
+	if err := s.authRepository.CreateEmailVerificationToken(verification); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Welcome!\n\nPlease confirm your email address by opening the link below:\n\n%s\n\n"+
+			"The link expires in %s. If you did not create an account, you can ignore this email.\n",
+			link.String(), s.verifyConfig.TokenTTL),
+	})
+}
+
+// newOpaqueToken returns a random URL-safe token. Only its hash is stored
+func newOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
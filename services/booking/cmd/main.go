@@ -1,13 +1,25 @@
 package main
 
 import (
+	"airbnb-clone/booking/internal/adapters/consumer"
+	httpserver "airbnb-clone/booking/internal/adapters/http_server"
+	"airbnb-clone/booking/internal/adapters/publisher"
+	"airbnb-clone/booking/internal/adapters/repository"
 	"airbnb-clone/booking/internal/config"
+	"airbnb-clone/booking/internal/domain/entity"
+	"airbnb-clone/booking/internal/domain/service"
+	"airbnb-clone/shared/authclient"
+	"airbnb-clone/shared/middleware"
+	"airbnb-clone/shared/outbox"
 	"context"
-	"fmt"
+	"expvar"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/segmentio/kafka-go"
+	"github.com/gin-gonic/gin"
 )
 
 const (
@@ -21,20 +33,80 @@ func main() {
 	log := createLogger(cfg.Env)
 	log.Info("booking app just started")
 
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: []string{"localhost:9092"},
-		Topic:   "apt-create",
-	})
-	defer reader.Close()
+	db, err := repository.NewPostgresDB(cfg)
+	if err != nil {
+		log.Error("failed to setup database connection")
+		os.Exit(1)
+	}
+
+	aptRepo := repository.NewApartmentRepository(db)
+	aptConsumer := consumer.NewApartmentConsumer(cfg.Kafka, service.NewApartmentService(aptRepo, log), log)
+	defer aptConsumer.Close()
+	go aptConsumer.Run(ctx)
+
+	bookingPublisher := publisher.NewKafkaPublisher(cfg.Kafka.Brokers, cfg.Kafka.BookingTopic)
+	defer bookingPublisher.Close()
+
+	relay := outbox.NewRelay[entity.BookingEvent](outbox.NewGormStore(db), bookingPublisher, outbox.Config{
+		PollInterval:   cfg.Outbox.PollInterval,
+		BatchSize:      cfg.Outbox.BatchSize,
+		PublishTimeout: cfg.Outbox.PublishTimeout,
+		MaxBackoff:     cfg.Outbox.MaxBackoff,
+	}, log)
+	go relay.Run(ctx)
 
-	for {
-		msg, err := reader.ReadMessage(ctx)
+	bookingService := service.NewBookingService(repository.NewBookingRepository(db), aptRepo, log)
+	jwks := middleware.NewJWKSCache(cfg.Auth.JWKSURL, cfg.Auth.JWKSCacheTTL)
+	validator := middleware.NewTokenValidator(jwks, cfg.Auth.Issuer, cfg.Auth.Audience, cfg.Auth.ClockSkew)
+	if cfg.Auth.GRPC.Address != "" {
+		authClient, err := authclient.New(authclient.Config{
+			Address:    cfg.Auth.GRPC.Address,
+			CAFile:     cfg.Auth.GRPC.CAFile,
+			ServerName: cfg.Auth.GRPC.ServerName,
+			CertFile:   cfg.Auth.GRPC.CertFile,
+			KeyFile:    cfg.Auth.GRPC.KeyFile,
+			Timeout:    cfg.Auth.GRPC.Timeout,
+			CacheTTL:   cfg.Auth.GRPC.CacheTTL,
+			CacheSize:  cfg.Auth.GRPC.CacheSize,
+		})
 		if err != nil {
-			break
+			log.Error("failed to setup auth client", slog.String("error", err.Error()))
+			os.Exit(1)
 		}
-		fmt.Printf("message at offset %d: %s = %s\n", msg.Offset, string(msg.Key), string(msg.Value))
+		defer authClient.Close()
+		validator.UseIntrospector(authClient)
+	}
+	if cfg.AdminAddress != "" {
+		go serveAdmin(log, cfg.AdminAddress)
+	}
+
+	r := setUpHttpServer(log, bookingService, validator)
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Error("invalid trusted proxies", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	if err := r.Run(cfg.Address); err != nil {
+		log.Error("Failed to start server:", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+	}
+}
+
+func setUpHttpServer(log *slog.Logger, bookingService service.BookingService, validator *middleware.TokenValidator) *gin.Engine {
+	r := gin.Default()
+	bookingController := httpserver.NewBookingController(log, bookingService)
+	httpserver.SetupBookingRoutes(r, bookingController, validator)
+	return r
+}
+
+// serveAdmin serves the runtime metrics on a listener of its own, so that they
+// are not exposed with the API
+func serveAdmin(log *slog.Logger, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	if err := http.ListenAndServe(address, mux); err != nil {
+		log.Error("admin server stopped", slog.String("error", err.Error()))
 	}
 }
 
@@ -0,0 +1,58 @@
+package consumer
+
+import (
+	"airbnb-clone/booking/internal/config"
+	"airbnb-clone/booking/internal/domain/entity"
+	"airbnb-clone/booking/internal/domain/service"
+	"airbnb-clone/shared/kafkaconsumer"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// NewApartmentConsumer keeps the apartment replica in sync with the apartment
+// events. Messages that cannot be applied end up in the dead-letter topic
+func NewApartmentConsumer(cfg config.Kafka, aptService service.ApartmentService, log *slog.Logger) *kafkaconsumer.Consumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: cfg.Brokers,
+		GroupID: cfg.GroupID,
+		Topic:   cfg.ApartmentTopic,
+	})
+
+	deadLetter := &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Brokers...),
+		Topic:                  cfg.DeadLetterTopic,
+		Balancer:               &kafka.Hash{},
+		AllowAutoTopicCreation: true,
+	}
+
+	handler := &apartmentHandler{aptService: aptService}
+	return kafkaconsumer.New(reader, deadLetter, handler.handle, kafkaconsumer.Config{
+		MaxRetries:   cfg.MaxRetries,
+		RetryBackoff: cfg.RetryBackoff,
+		MaxBackoff:   cfg.MaxBackoff,
+	}, log)
+}
+
+type apartmentHandler struct {
+	aptService service.ApartmentService
+}
+
+func (h *apartmentHandler) handle(_ context.Context, msg kafka.Message) error {
+	var event entity.ApartmentEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return kafkaconsumer.Permanent(err)
+	}
+
+	if err := h.aptService.SyncApartment(&event); err != nil {
+		if errors.Is(err, service.ErrInvalidEvent) {
+			return kafkaconsumer.Permanent(err)
+		}
+		return err
+	}
+
+	return nil
+}
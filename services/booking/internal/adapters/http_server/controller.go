@@ -0,0 +1,171 @@
+package httpserver
+
+import (
+	"airbnb-clone/booking/internal/domain/entity"
+	"airbnb-clone/booking/internal/domain/service"
+	"airbnb-clone/shared/middleware"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const dateLayout = "02-01-2006"
+
+type BookingController interface {
+	CreateBooking(ctx *gin.Context)
+	GetBooking(ctx *gin.Context)
+	GetYourBookings(ctx *gin.Context)
+	CancelBooking(ctx *gin.Context)
+}
+
+type bookingController struct {
+	bookingService service.BookingService
+	log            *slog.Logger
+}
+
+func NewBookingController(logger *slog.Logger, bookingService service.BookingService) BookingController {
+	return &bookingController{log: logger, bookingService: bookingService}
+}
+
+func (c *bookingController) CreateBooking(ctx *gin.Context) {
+	const fn = "adapters.controller.CreateBooking"
+	log := c.log.With(slog.String("fn", fn))
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var request createBookingRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	checkIn, err := time.Parse(dateLayout, request.CheckIn)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid check-in date format. Use DD-MM-YYYY"})
+		return
+	}
+	checkOut, err := time.Parse(dateLayout, request.CheckOut)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid check-out date format. Use DD-MM-YYYY"})
+		return
+	}
+
+	booking, err := c.bookingService.CreateBooking(&entity.CreateBookingRequest{
+		ApartmentID: request.ApartmentID,
+		CheckIn:     checkIn,
+		CheckOut:    checkOut,
+		Guests:      request.Guests,
+	}, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidInput) || errors.Is(err, service.ErrInvalidDates) ||
+			errors.Is(err, service.ErrTooManyGuests) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrAptNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Apartment with provided ID was not found"})
+			return
+		}
+		if errors.Is(err, service.ErrDatesUnavailable) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Apartment is already booked for the selected dates"})
+			return
+		}
+		log.Error("failed to create a booking", slog.String("error", err.Error()))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, booking)
+}
+
+func (c *bookingController) GetBooking(ctx *gin.Context) {
+	const fn = "adapters.controller.GetBooking"
+	log := c.log.With(slog.String("fn", fn))
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	bookingID := ctx.Param("id")
+	if bookingID == "" {
+		log.Error("booking id was not provided")
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Booking ID was not provided"})
+		return
+	}
+
+	booking, err := c.bookingService.GetBookingByID(bookingID, userID)
+	if err != nil {
+		c.writeServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, booking)
+}
+
+func (c *bookingController) GetYourBookings(ctx *gin.Context) {
+	const fn = "adapters.controller.GetYourBookings"
+	log := c.log.With(slog.String("fn", fn))
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		log.Error("failed to get user id out of context", slog.String("error", err.Error()))
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	bookings, err := c.bookingService.GetGuestBookings(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, bookings)
+}
+
+func (c *bookingController) CancelBooking(ctx *gin.Context) {
+	const fn = "adapters.controller.CancelBooking"
+	log := c.log.With(slog.String("fn", fn))
+
+	userID, err := middleware.GetUserIDFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	bookingID := ctx.Param("id")
+	if bookingID == "" {
+		log.Error("booking id was not provided")
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Booking ID was not provided"})
+		return
+	}
+
+	booking, err := c.bookingService.CancelBooking(bookingID, userID)
+	if err != nil {
+		c.writeServiceError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, booking)
+}
+
+func (c *bookingController) writeServiceError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrBookingNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Booking with provided ID was not found"})
+	case errors.Is(err, service.ErrForbidden):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to access this booking"})
+	case errors.Is(err, service.ErrAlreadyCancelled):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Booking is already cancelled"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,47 @@
+package httpserver
+
+import (
+	"airbnb-clone/booking/internal/domain/entity"
+	"airbnb-clone/booking/internal/domain/service"
+	"airbnb-clone/shared/middleware"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeBookingService fails every booking with createErr
+type fakeBookingService struct {
+	service.BookingService
+	createErr error
+}
+
+func (s *fakeBookingService) CreateBooking(*entity.CreateBookingRequest, string) (*entity.BookingResponse, error) {
+	return nil, s.createErr
+}
+
+func TestCreateBookingDatesUnavailableIsConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewBookingController(slog.New(slog.NewTextHandler(io.Discard, nil)),
+		&fakeBookingService{createErr: fmt.Errorf("create: %w", service.ErrDatesUnavailable)})
+
+	r := gin.New()
+	r.POST("/booking", func(c *gin.Context) {
+		c.Set(middleware.UserIDKey, "guest-1")
+	}, controller.CreateBooking)
+
+	body := `{"apartment_id":"apt-1","check_in":"01-06-2030","check_out":"03-06-2030","guests":1}`
+	req := httptest.NewRequest(http.MethodPost, "/booking", strings.NewReader(body))
+	req.Header.Set("Content-Type", gin.MIMEJSON)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d, body %s", w.Code, http.StatusConflict, w.Body.String())
+	}
+}
@@ -0,0 +1,8 @@
+package httpserver
+
+type createBookingRequest struct {
+	ApartmentID string `json:"apartment_id" binding:"required"`
+	CheckIn     string `json:"check_in" binding:"required"`
+	CheckOut    string `json:"check_out" binding:"required"`
+	Guests      int    `json:"guests" binding:"required,min=1"`
+}
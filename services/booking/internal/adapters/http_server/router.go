@@ -0,0 +1,18 @@
+package httpserver
+
+import (
+	"airbnb-clone/shared/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupBookingRoutes(r *gin.Engine, bookingController BookingController, validator *middleware.TokenValidator) {
+	authGroup := r.Group("/")
+	authGroup.Use(middleware.AuthMiddleware(validator))
+	{
+		authGroup.POST("/booking", middleware.RequirePermission(middleware.PermissionCreateBooking), bookingController.CreateBooking)
+		authGroup.GET("/booking/:id", bookingController.GetBooking)
+		authGroup.GET("/bookings", bookingController.GetYourBookings)
+		authGroup.POST("/booking/:id/cancel", bookingController.CancelBooking)
+	}
+}
@@ -0,0 +1,11 @@
+package publisher
+
+import (
+	"airbnb-clone/booking/internal/domain/entity"
+	"context"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event *entity.BookingEvent) error
+	Close() error
+}
@@ -1 +1,60 @@
 package repository
+
+import (
+	"airbnb-clone/booking/internal/domain/entity"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ApartmentRepository interface {
+	UpsertApartment(apartment *entity.Apartment) error
+	GetApartment(id string) (*entity.Apartment, error)
+}
+
+type apartmentStorage struct {
+	db *gorm.DB
+}
+
+func NewApartmentRepository(db *gorm.DB) ApartmentRepository {
+	return &apartmentStorage{db: db}
+}
+
+// UpsertApartment stores the apartment unless a newer event was already applied,
+// so redelivered or reordered messages never roll the replica back
+func (s *apartmentStorage) UpsertApartment(apartment *entity.Apartment) error {
+	const fn = "adapters.repository.UpsertApartment"
+
+	result := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"host_id", "price_per_night", "max_guests", "city", "deleted", "occurred_at", "updated_at",
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "apartments.occurred_at < excluded.occurred_at"},
+		}},
+	}).Create(apartment)
+	if result.Error != nil {
+		return fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return nil
+}
+
+func (s *apartmentStorage) GetApartment(id string) (*entity.Apartment, error) {
+	const fn = "adapters.repository.GetApartment"
+	var apartment entity.Apartment
+
+	result := s.db.First(&apartment, "id = ? AND deleted = false", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return &entity.Apartment{}, ErrAptNotFound
+		}
+
+		return &entity.Apartment{}, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return &apartment, nil
+}
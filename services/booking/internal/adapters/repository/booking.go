@@ -1 +1,117 @@
 package repository
+
+import (
+	"airbnb-clone/booking/internal/domain/entity"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	bookingOverlapConstraint = "bookings_no_overlap"
+	pgExclusionViolation     = "23P01"
+)
+
+type BookingRepository interface {
+	CreateBooking(booking *entity.Booking) error
+	GetBooking(id string) (*entity.Booking, error)
+	GetBookingsByGuest(guestID string) ([]entity.Booking, error)
+	// UpdateBookingStatus moves the booking from one status to another. It
+	// returns ErrStatusChanged when the booking is not in the from status, e.g.
+	// after a concurrent update
+	UpdateBookingStatus(id string, from string, to string) error
+	AddOutboxMessage(message *entity.OutboxMessage) error
+	WithinTransaction(fn func(repo BookingRepository) error) error
+}
+
+type bookingStorage struct {
+	db *gorm.DB
+}
+
+func NewBookingRepository(db *gorm.DB) BookingRepository {
+	return &bookingStorage{db: db}
+}
+
+// WithinTransaction runs fn against a repository bound to a single transaction,
+// so booking changes and their outbox messages are committed together
+func (s *bookingStorage) WithinTransaction(fn func(repo BookingRepository) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&bookingStorage{db: tx})
+	})
+}
+
+func (s *bookingStorage) CreateBooking(booking *entity.Booking) error {
+	const fn = "adapters.repository.CreateBooking"
+
+	result := s.db.Create(booking)
+	if result.Error != nil {
+		if isDatesOverlap(result.Error) {
+			return ErrDatesOverlap
+		}
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	return nil
+}
+
+// isDatesOverlap reports whether err is a violation of the exclusion
+// constraint that keeps the bookings of an apartment from overlapping
+func isDatesOverlap(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgExclusionViolation && pgErr.ConstraintName == bookingOverlapConstraint
+}
+
+func (s *bookingStorage) GetBooking(id string) (*entity.Booking, error) {
+	const fn = "adapters.repository.GetBooking"
+	var booking entity.Booking
+
+	result := s.db.First(&booking, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return &entity.Booking{}, ErrBookingNotFound
+		}
+
+		return &entity.Booking{}, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return &booking, nil
+}
+
+func (s *bookingStorage) GetBookingsByGuest(guestID string) ([]entity.Booking, error) {
+	const fn = "adapters.repository.GetBookingsByGuest"
+	var bookings []entity.Booking
+
+	result := s.db.Where("guest_id = ?", guestID).Order("check_in DESC").Find(&bookings)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return bookings, nil
+}
+
+func (s *bookingStorage) UpdateBookingStatus(id string, from string, to string) error {
+	const fn = "adapters.repository.UpdateBookingStatus"
+
+	result := s.db.Model(&entity.Booking{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	if result.Error != nil {
+		return fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrStatusChanged
+	}
+
+	return nil
+}
+
+func (s *bookingStorage) AddOutboxMessage(message *entity.OutboxMessage) error {
+	const fn = "adapters.repository.AddOutboxMessage"
+
+	result := s.db.Create(message)
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	return nil
+}
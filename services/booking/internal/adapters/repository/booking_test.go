@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsDatesOverlap(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "overlap", err: &pgconn.PgError{Code: pgExclusionViolation, ConstraintName: bookingOverlapConstraint}, want: true},
+		{name: "wrapped overlap", err: fmt.Errorf("insert: %w", &pgconn.PgError{Code: pgExclusionViolation, ConstraintName: bookingOverlapConstraint}), want: true},
+		{name: "other constraint", err: &pgconn.PgError{Code: pgExclusionViolation, ConstraintName: "other"}},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505", ConstraintName: "bookings_pkey"}},
+		{name: "not a postgres error", err: errors.New("connection refused")},
+	}
+
+	for _, tt := range tests {
+		if got := isDatesOverlap(tt.err); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
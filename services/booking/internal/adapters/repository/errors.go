@@ -0,0 +1,10 @@
+package repository
+
+import "errors"
+
+var (
+	ErrBookingNotFound = errors.New("booking with provided ID was not found")
+	ErrDatesOverlap    = errors.New("booking dates overlap with an existing booking")
+	ErrAptNotFound     = errors.New("apartment with provided ID was not found")
+	ErrStatusChanged   = errors.New("booking is no longer in the expected status")
+)
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"airbnb-clone/booking/internal/config"
+	"airbnb-clone/booking/internal/domain/entity"
+	"fmt"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func NewPostgresDB(cfg *config.Config) (*gorm.DB, error) {
+	const fn = "adapters.repository.NewPostgresDB"
+	dsn := fmt.Sprintf("host=%s user=%s "+
+		"password=%s dbname=%s port=%d sslmode=disable",
+		cfg.PostgresConnect.Host, cfg.PostgresConnect.User, cfg.PostgresConnect.Password, cfg.PostgresConnect.DatabaseName, cfg.PostgresConnect.Port)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := Migrate(db); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return db, nil
+}
+
+// Migrate creates the tables and constraints the repositories rely on
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(&entity.Booking{}, &entity.Apartment{}, &entity.OutboxMessage{})
+	if err != nil {
+		return err
+	}
+
+	return migrateBookingOverlapConstraint(db)
+}
+
+// migrateBookingOverlapConstraint makes postgres reject confirmed bookings whose
+// [check_in, check_out) ranges intersect for the same apartment, so two guests
+// racing for the same nights can never both succeed
+func migrateBookingOverlapConstraint(db *gorm.DB) error {
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS btree_gist").Error; err != nil {
+		return err
+	}
+
+	return db.Exec(`
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '` + bookingOverlapConstraint + `') THEN
+		ALTER TABLE bookings ADD CONSTRAINT ` + bookingOverlapConstraint + `
+			EXCLUDE USING gist (
+				apartment_id WITH =,
+				daterange(check_in, check_out, '[)') WITH &&
+			) WHERE (status = 'confirmed');
+	END IF;
+END
+$$;`).Error
+}
@@ -12,12 +12,22 @@ type Config struct {
 	Env             string `yaml:"env" env-default:"local"`
 	HttpServer      `yaml:"http_server"`
 	PostgresConnect `yaml:"postgres_storage"`
+	Auth            `yaml:"auth"`
+	Kafka           `yaml:"kafka"`
+	Outbox          `yaml:"outbox"`
 }
 
 type HttpServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// TrustedProxies are the CIDRs of the reverse proxies in front of the
+	// service. X-Forwarded-For is only believed when the request comes from one
+	// of them; empty trusts no proxy and the client IP is the peer address
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" env-separator:","`
+	// AdminAddress serves /debug/vars apart from the API. It must not be
+	// reachable from the public network; empty disables it
+	AdminAddress string `yaml:"admin_address" env-default:"localhost:9090"`
 }
 
 type PostgresConnect struct {
@@ -28,6 +38,51 @@ type PostgresConnect struct {
 	DatabaseName string `yaml:"dbname"  env-required:"true"`
 }
 
+type Kafka struct {
+	Brokers         []string      `yaml:"brokers" env-default:"localhost:9092"`
+	GroupID         string        `yaml:"group_id" env-default:"booking-service"`
+	ApartmentTopic  string        `yaml:"apartment_topic" env-default:"apt-create"`
+	DeadLetterTopic string        `yaml:"dead_letter_topic" env-default:"apt-create-dlq"`
+	BookingTopic    string        `yaml:"booking_topic" env-default:"booking-events"`
+	MaxRetries      int           `yaml:"max_retries" env-default:"5"`
+	RetryBackoff    time.Duration `yaml:"retry_backoff" env-default:"500ms"`
+	// MaxBackoff caps the wait between attempts while a message can neither be
+	// applied nor parked in the dead-letter topic
+	MaxBackoff time.Duration `yaml:"max_backoff" env-default:"1m"`
+}
+
+type Outbox struct {
+	PollInterval   time.Duration `yaml:"poll_interval" env-default:"1s"`
+	BatchSize      int           `yaml:"batch_size" env-default:"100"`
+	PublishTimeout time.Duration `yaml:"publish_timeout" env-default:"5s"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" env-default:"1m"`
+}
+
+type Auth struct {
+	JWKSURL      string        `yaml:"jwks_url" env-default:"http://auth-service:8000/.well-known/jwks.json"`
+	JWKSCacheTTL time.Duration `yaml:"jwks_cache_ttl" env-default:"5m"`
+	Issuer       string        `yaml:"issuer" env-default:"airbnb-clone-auth"`
+	Audience     string        `yaml:"audience" env-default:"airbnb-clone"`
+	ClockSkew    time.Duration `yaml:"clock_skew" env-default:"30s"`
+	GRPC         AuthGRPC      `yaml:"grpc"`
+}
+
+// AuthGRPC configures the client of the internal API of the auth service.
+// Once Address is set every token is also introspected there, with answers
+// cached for CacheTTL. TLS is used once CAFile is set, and a client
+// certificate is presented once CertFile and KeyFile are set as well
+type AuthGRPC struct {
+	Address string `yaml:"address"`
+	CAFile  string `yaml:"ca_file" env:"AUTH_GRPC_CA_FILE"`
+	// ServerName overrides the name the server certificate is checked for
+	ServerName string        `yaml:"server_name"`
+	CertFile   string        `yaml:"cert_file" env:"AUTH_GRPC_CERT_FILE"`
+	KeyFile    string        `yaml:"key_file" env:"AUTH_GRPC_KEY_FILE"`
+	Timeout    time.Duration `yaml:"timeout" env-default:"2s"`
+	CacheTTL   time.Duration `yaml:"cache_ttl" env-default:"30s"`
+	CacheSize  int           `yaml:"cache_size" env-default:"10000"`
+}
+
 func MustLoad() *Config {
 	configPath := "config/local.yaml"
 
@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+const (
+	EventApartmentCreated = "apartment.created"
+	EventApartmentUpdated = "apartment.updated"
+	EventApartmentDeleted = "apartment.deleted"
+)
+
+// Apartment is a local read-model of the listing owned by the apartment service.
+// It only keeps the fields booking validation needs
+type Apartment struct {
+	ID            string    `gorm:"primaryKey"`
+	HostID        string    `gorm:"not null;index"`
+	PricePerNight float64   `gorm:"not null"`
+	MaxGuests     int       `gorm:"not null"`
+	City          string    `gorm:"size:100"`
+	Deleted       bool      `gorm:"default:false"`
+	OccurredAt    time.Time `gorm:"not null"` // time of the last applied event
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+type ApartmentSnapshot struct {
+	ID            string  `json:"id"`
+	HostID        string  `json:"host_id"`
+	PricePerNight float64 `json:"price_per_night"`
+	MaxGuests     int     `json:"max_guests"`
+	City          string  `json:"city"`
+}
+
+type ApartmentEvent struct {
+	EventID    string            `json:"event_id"`
+	Type       string            `json:"type"`
+	Version    int               `json:"version"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Apartment  ApartmentSnapshot `json:"apartment"`
+}
@@ -0,0 +1,46 @@
+package entity
+
+import "time"
+
+const (
+	StatusConfirmed = "confirmed"
+	StatusCancelled = "cancelled"
+)
+
+type Booking struct {
+	ID          string    `gorm:"type:uuid;primaryKey"`
+	GuestID     string    `gorm:"type:uuid;not null;index"`
+	ApartmentID string    `gorm:"not null;index"`
+	CheckIn     time.Time `gorm:"type:date;not null"`
+	CheckOut    time.Time `gorm:"type:date;not null"`
+	Guests      int       `gorm:"not null"`
+	Status      string    `gorm:"size:20;not null;default:confirmed"`
+	TotalPrice  float64   `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+// Nights returns the number of nights between check-in and check-out
+func (b *Booking) Nights() int {
+	return int(b.CheckOut.Sub(b.CheckIn).Hours() / 24)
+}
+
+type CreateBookingRequest struct {
+	ApartmentID string
+	CheckIn     time.Time
+	CheckOut    time.Time
+	Guests      int
+}
+
+type BookingResponse struct {
+	ID          string    `json:"id"`
+	GuestID     string    `json:"guest_id"`
+	ApartmentID string    `json:"apartment_id"`
+	CheckIn     time.Time `json:"check_in"`
+	CheckOut    time.Time `json:"check_out"`
+	Guests      int       `json:"guests"`
+	Status      string    `json:"status"`
+	TotalPrice  float64   `json:"total_price"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+const (
+	EventBookingConfirmed = "booking.confirmed"
+	EventBookingCancelled = "booking.cancelled"
+
+	BookingEventVersion = 1
+)
+
+type BookingEvent struct {
+	EventID    string          `json:"event_id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Booking    BookingSnapshot `json:"booking"`
+}
+
+type BookingSnapshot struct {
+	ID          string    `json:"id"`
+	ApartmentID string    `json:"apartment_id"`
+	CheckIn     time.Time `json:"check_in"`
+	CheckOut    time.Time `json:"check_out"`
+	Guests      int       `json:"guests"`
+	Status      string    `json:"status"`
+}
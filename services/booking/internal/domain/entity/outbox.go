@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+type OutboxMessage struct {
+	ID            string     `gorm:"type:uuid;primaryKey"`
+	AggregateID   string     `gorm:"not null;index"`
+	EventType     string     `gorm:"size:100;not null"`
+	Payload       []byte     `gorm:"type:jsonb;not null"`
+	Attempts      int        `gorm:"default:0"`
+	LastError     string     `gorm:"type:text"`
+	NextAttemptAt time.Time  `gorm:"not null;index"`
+	PublishedAt   *time.Time `gorm:"index"`
+	DeadAt        *time.Time `gorm:"index"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
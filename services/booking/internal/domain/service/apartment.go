@@ -0,0 +1,55 @@
+package service
+
+import (
+	"airbnb-clone/booking/internal/adapters/repository"
+	"airbnb-clone/booking/internal/domain/entity"
+	"fmt"
+	"log/slog"
+)
+
+type ApartmentService interface {
+	SyncApartment(event *entity.ApartmentEvent) error
+}
+
+type apartmentService struct {
+	repo repository.ApartmentRepository
+	log  *slog.Logger
+}
+
+func NewApartmentService(repo repository.ApartmentRepository, log *slog.Logger) ApartmentService {
+	return &apartmentService{repo: repo, log: log}
+}
+
+// SyncApartment applies an apartment lifecycle event to the local read-model
+func (s *apartmentService) SyncApartment(event *entity.ApartmentEvent) error {
+	const fn = "domain.service.SyncApartment"
+	log := s.log.With(slog.String("fn", fn), slog.String("event_id", event.EventID))
+
+	if event.Apartment.ID == "" || event.OccurredAt.IsZero() {
+		return fmt.Errorf("%s: %w", fn, ErrInvalidEvent)
+	}
+
+	apartment := &entity.Apartment{
+		ID:            event.Apartment.ID,
+		HostID:        event.Apartment.HostID,
+		PricePerNight: event.Apartment.PricePerNight,
+		MaxGuests:     event.Apartment.MaxGuests,
+		City:          event.Apartment.City,
+		OccurredAt:    event.OccurredAt,
+	}
+
+	switch event.Type {
+	case entity.EventApartmentCreated, entity.EventApartmentUpdated:
+	case entity.EventApartmentDeleted:
+		apartment.Deleted = true
+	default:
+		return fmt.Errorf("%s: %w: %s", fn, ErrInvalidEvent, event.Type)
+	}
+
+	if err := s.repo.UpsertApartment(apartment); err != nil {
+		log.Error("failed to upsert apartment", slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
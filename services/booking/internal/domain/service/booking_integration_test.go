@@ -0,0 +1,176 @@
+//go:build integration
+
+package service_test
+
+import (
+	"airbnb-clone/booking/internal/adapters/repository"
+	"airbnb-clone/booking/internal/domain/entity"
+	"airbnb-clone/booking/internal/domain/service"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB connects to the database in TEST_POSTGRES_DSN, e.g.
+// "host=localhost user=postgres password=postgres dbname=booking_test sslmode=disable".
+// Run with: go test -tags integration ./...
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := repository.Migrate(db); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+	return db
+}
+
+func TestCreateBookingConcurrentOverlapOneWins(t *testing.T) {
+	db := openTestDB(t)
+	aptRepo := repository.NewApartmentRepository(db)
+	bookingService := service.NewBookingService(repository.NewBookingRepository(db), aptRepo,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	apartment := &entity.Apartment{ID: uuid.New().String(), HostID: uuid.New().String(), PricePerNight: 100,
+		MaxGuests: 4, OccurredAt: time.Now()}
+	if err := aptRepo.UpsertApartment(apartment); err != nil {
+		t.Fatalf("upsert apartment: %v", err)
+	}
+
+	checkIn := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 1, 0)
+	const guests = 8
+
+	var wg sync.WaitGroup
+	errs := make([]error, guests)
+	start := make(chan struct{})
+	for i := range guests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			// every range includes the night that starts two days after checkIn
+			request := &entity.CreateBookingRequest{
+				ApartmentID: apartment.ID,
+				CheckIn:     checkIn.AddDate(0, 0, i%3),
+				CheckOut:    checkIn.AddDate(0, 0, 3+i%2),
+				Guests:      1,
+			}
+			_, errs[i] = bookingService.CreateBooking(request, uuid.New().String())
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, service.ErrDatesUnavailable):
+		default:
+			t.Errorf("guest %d: unexpected error %v", i, err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("got %d confirmed bookings, want exactly 1", succeeded)
+	}
+}
+
+func TestCreateBookingAdjacentRangesDoNotConflict(t *testing.T) {
+	db := openTestDB(t)
+	aptRepo := repository.NewApartmentRepository(db)
+	bookingService := service.NewBookingService(repository.NewBookingRepository(db), aptRepo,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	apartment := &entity.Apartment{ID: uuid.New().String(), HostID: uuid.New().String(), PricePerNight: 100,
+		MaxGuests: 4, OccurredAt: time.Now()}
+	if err := aptRepo.UpsertApartment(apartment); err != nil {
+		t.Fatalf("upsert apartment: %v", err)
+	}
+
+	checkIn := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 1, 0)
+	first := &entity.CreateBookingRequest{ApartmentID: apartment.ID, CheckIn: checkIn, CheckOut: checkIn.AddDate(0, 0, 2), Guests: 1}
+	second := &entity.CreateBookingRequest{ApartmentID: apartment.ID, CheckIn: checkIn.AddDate(0, 0, 2), CheckOut: checkIn.AddDate(0, 0, 4), Guests: 1}
+
+	if _, err := bookingService.CreateBooking(first, uuid.New().String()); err != nil {
+		t.Fatalf("first booking: %v", err)
+	}
+	if _, err := bookingService.CreateBooking(second, uuid.New().String()); err != nil {
+		t.Fatalf("booking from the check-out day: %v", err)
+	}
+}
+
+func TestCancelBookingConcurrentCancelsOnce(t *testing.T) {
+	db := openTestDB(t)
+	aptRepo := repository.NewApartmentRepository(db)
+	bookingService := service.NewBookingService(repository.NewBookingRepository(db), aptRepo,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	apartment := &entity.Apartment{ID: uuid.New().String(), HostID: uuid.New().String(), PricePerNight: 100,
+		MaxGuests: 4, OccurredAt: time.Now()}
+	if err := aptRepo.UpsertApartment(apartment); err != nil {
+		t.Fatalf("upsert apartment: %v", err)
+	}
+
+	checkIn := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 1, 0)
+	guestID := uuid.New().String()
+	booking, err := bookingService.CreateBooking(&entity.CreateBookingRequest{ApartmentID: apartment.ID, CheckIn: checkIn,
+		CheckOut: checkIn.AddDate(0, 0, 2), Guests: 1}, guestID)
+	if err != nil {
+		t.Fatalf("create booking: %v", err)
+	}
+
+	const attempts = 8
+	var wg sync.WaitGroup
+	errs := make([]error, attempts)
+	start := make(chan struct{})
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[i] = bookingService.CancelBooking(booking.ID, guestID)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, service.ErrAlreadyCancelled):
+		default:
+			t.Errorf("attempt %d: unexpected error %v", i, err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("got %d successful cancels, want exactly 1", succeeded)
+	}
+
+	var events int64
+	if err := db.Model(&entity.OutboxMessage{}).Where("aggregate_id = ? AND event_type = ?", apartment.ID,
+		entity.EventBookingCancelled).Count(&events).Error; err != nil {
+		t.Fatalf("count outbox messages: %v", err)
+	}
+	if events != 1 {
+		t.Errorf("got %d cancel events, want exactly 1", events)
+	}
+}
@@ -0,0 +1,15 @@
+package service
+
+import "errors"
+
+var (
+	ErrBookingNotFound  = errors.New("booking not found")
+	ErrAptNotFound      = errors.New("apartment not found")
+	ErrTooManyGuests    = errors.New("number of guests exceeds apartment capacity")
+	ErrInvalidEvent     = errors.New("invalid apartment event")
+	ErrInvalidInput     = errors.New("invalid input data")
+	ErrInvalidDates     = errors.New("invalid booking dates")
+	ErrForbidden        = errors.New("access to the booking is forbidden")
+	ErrAlreadyCancelled = errors.New("booking is already cancelled")
+	ErrDatesUnavailable = errors.New("apartment is not available for the selected dates")
+)
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"airbnb-clone/booking/internal/adapters/repository"
+	"airbnb-clone/booking/internal/domain/entity"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BookingService interface {
+	CreateBooking(req *entity.CreateBookingRequest, guestID string) (*entity.BookingResponse, error)
+	GetBookingByID(id string, userID string) (*entity.BookingResponse, error)
+	GetGuestBookings(guestID string) ([]entity.BookingResponse, error)
+	CancelBooking(id string, userID string) (*entity.BookingResponse, error)
+}
+
+type bookingService struct {
+	repo    repository.BookingRepository
+	aptRepo repository.ApartmentRepository
+	log     *slog.Logger
+}
+
+func NewBookingService(repo repository.BookingRepository, aptRepo repository.ApartmentRepository, log *slog.Logger) BookingService {
+	return &bookingService{repo: repo, aptRepo: aptRepo, log: log}
+}
+
+func (s *bookingService) CreateBooking(req *entity.CreateBookingRequest, guestID string) (*entity.BookingResponse, error) {
+	const fn = "domain.service.CreateBooking"
+	log := s.log.With(slog.String("fn", fn))
+
+	if req.ApartmentID == "" || guestID == "" || req.Guests < 1 {
+		log.Error("failed to create a booking. no required fields")
+		return nil, fmt.Errorf("%s: %w", fn, ErrInvalidInput)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if !req.CheckOut.After(req.CheckIn) || req.CheckIn.Before(today) {
+		return nil, fmt.Errorf("%s: %w", fn, ErrInvalidDates)
+	}
+
+	apt, err := s.aptRepo.GetApartment(req.ApartmentID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAptNotFound) {
+			return nil, ErrAptNotFound
+		}
+		log.Error("failed to get apartment", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if req.Guests > apt.MaxGuests {
+		return nil, fmt.Errorf("%s: %w", fn, ErrTooManyGuests)
+	}
+
+	booking := &entity.Booking{
+		ID:          uuid.New().String(),
+		GuestID:     guestID,
+		ApartmentID: req.ApartmentID,
+		CheckIn:     req.CheckIn,
+		CheckOut:    req.CheckOut,
+		Guests:      req.Guests,
+		Status:      entity.StatusConfirmed,
+	}
+	booking.TotalPrice = float64(booking.Nights()) * apt.PricePerNight
+
+	err = s.repo.WithinTransaction(func(repo repository.BookingRepository) error {
+		if err := repo.CreateBooking(booking); err != nil {
+			return err
+		}
+		return enqueueEvent(repo, entity.EventBookingConfirmed, booking)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrDatesOverlap) {
+			return nil, ErrDatesUnavailable
+		}
+		log.Error("failed to save booking", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return toBookingResponse(booking), nil
+}
+
+func (s *bookingService) GetBookingByID(id string, userID string) (*entity.BookingResponse, error) {
+	const fn = "domain.service.GetBookingByID"
+	log := s.log.With(slog.String("fn", fn))
+
+	booking, err := s.repo.GetBooking(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookingNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		log.Error("failed to get booking", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if booking.GuestID != userID {
+		return nil, ErrForbidden
+	}
+
+	return toBookingResponse(booking), nil
+}
+
+func (s *bookingService) GetGuestBookings(guestID string) ([]entity.BookingResponse, error) {
+	const fn = "domain.service.GetGuestBookings"
+	log := s.log.With(slog.String("fn", fn))
+
+	bookings, err := s.repo.GetBookingsByGuest(guestID)
+	if err != nil {
+		log.Error("failed to get guest bookings", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	resp := make([]entity.BookingResponse, 0, len(bookings))
+	for i := range bookings {
+		resp = append(resp, *toBookingResponse(&bookings[i]))
+	}
+
+	return resp, nil
+}
+
+func (s *bookingService) CancelBooking(id string, userID string) (*entity.BookingResponse, error) {
+	const fn = "domain.service.CancelBooking"
+	log := s.log.With(slog.String("fn", fn))
+
+	booking, err := s.repo.GetBooking(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookingNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		log.Error("failed to get booking", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if booking.GuestID != userID {
+		return nil, ErrForbidden
+	}
+
+	if booking.Status == entity.StatusCancelled {
+		return nil, ErrAlreadyCancelled
+	}
+
+	booking.Status = entity.StatusCancelled
+	err = s.repo.WithinTransaction(func(repo repository.BookingRepository) error {
+		// a concurrent cancel may have won since the booking was read; only the
+		// one that changes the row publishes the event
+		if err := repo.UpdateBookingStatus(id, entity.StatusConfirmed, entity.StatusCancelled); err != nil {
+			if errors.Is(err, repository.ErrStatusChanged) {
+				return ErrAlreadyCancelled
+			}
+			return err
+		}
+		return enqueueEvent(repo, entity.EventBookingCancelled, booking)
+	})
+	if err != nil {
+		if errors.Is(err, ErrAlreadyCancelled) {
+			return nil, ErrAlreadyCancelled
+		}
+		log.Error("failed to cancel booking", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return toBookingResponse(booking), nil
+}
+
+// enqueueEvent stores the event in the outbox within the caller's transaction.
+// The outbox relay publishes it once the transaction is committed
+func enqueueEvent(repo repository.BookingRepository, eventType string, booking *entity.Booking) error {
+	event := &entity.BookingEvent{
+		EventID:    uuid.New().String(),
+		Type:       eventType,
+		Version:    entity.BookingEventVersion,
+		OccurredAt: time.Now().UTC(),
+		Booking: entity.BookingSnapshot{
+			ID:          booking.ID,
+			ApartmentID: booking.ApartmentID,
+			CheckIn:     booking.CheckIn,
+			CheckOut:    booking.CheckOut,
+			Guests:      booking.Guests,
+			Status:      booking.Status,
+		},
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return repo.AddOutboxMessage(&entity.OutboxMessage{
+		ID:            event.EventID,
+		AggregateID:   booking.ApartmentID,
+		EventType:     eventType,
+		Payload:       payload,
+		NextAttemptAt: event.OccurredAt,
+	})
+}
+
+func toBookingResponse(booking *entity.Booking) *entity.BookingResponse {
+	return &entity.BookingResponse{
+		ID:          booking.ID,
+		GuestID:     booking.GuestID,
+		ApartmentID: booking.ApartmentID,
+		CheckIn:     booking.CheckIn,
+		CheckOut:    booking.CheckOut,
+		Guests:      booking.Guests,
+		Status:      booking.Status,
+		TotalPrice:  booking.TotalPrice,
+		CreatedAt:   booking.CreatedAt,
+		UpdatedAt:   booking.UpdatedAt,
+	}
+}
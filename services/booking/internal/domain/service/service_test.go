@@ -0,0 +1,67 @@
+package service
+
+import (
+	"airbnb-clone/booking/internal/adapters/repository"
+	"airbnb-clone/booking/internal/domain/entity"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// fakeBookingRepository fails every booking with createErr and keeps the
+// outbox messages of the transactions that succeed
+type fakeBookingRepository struct {
+	repository.BookingRepository
+	createErr error
+	outbox    []entity.OutboxMessage
+}
+
+func (r *fakeBookingRepository) CreateBooking(*entity.Booking) error {
+	return r.createErr
+}
+
+func (r *fakeBookingRepository) AddOutboxMessage(message *entity.OutboxMessage) error {
+	r.outbox = append(r.outbox, *message)
+	return nil
+}
+
+// WithinTransaction drops the outbox messages of a failed transaction, as a
+// rollback would
+func (r *fakeBookingRepository) WithinTransaction(fn func(repo repository.BookingRepository) error) error {
+	tx := &fakeBookingRepository{createErr: r.createErr}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	r.outbox = append(r.outbox, tx.outbox...)
+	return nil
+}
+
+type fakeApartmentRepository struct {
+	repository.ApartmentRepository
+	apartment *entity.Apartment
+}
+
+func (r *fakeApartmentRepository) GetApartment(id string) (*entity.Apartment, error) {
+	if r.apartment == nil || r.apartment.ID != id {
+		return nil, repository.ErrAptNotFound
+	}
+	return r.apartment, nil
+}
+
+func TestCreateBookingMapsOverlapToDatesUnavailable(t *testing.T) {
+	repo := &fakeBookingRepository{createErr: repository.ErrDatesOverlap}
+	aptRepo := &fakeApartmentRepository{apartment: &entity.Apartment{ID: "apt-1", PricePerNight: 100, MaxGuests: 2}}
+	s := NewBookingService(repo, aptRepo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	checkIn := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	_, err := s.CreateBooking(&entity.CreateBookingRequest{ApartmentID: "apt-1", CheckIn: checkIn,
+		CheckOut: checkIn.AddDate(0, 0, 2), Guests: 1}, "guest-1")
+	if !errors.Is(err, ErrDatesUnavailable) {
+		t.Fatalf("got %v, want %v", err, ErrDatesUnavailable)
+	}
+	if len(repo.outbox) != 0 {
+		t.Errorf("an event was stored for the rejected booking: %v", repo.outbox)
+	}
+}
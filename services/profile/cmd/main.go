@@ -2,12 +2,23 @@ package main
 
 import (
 	httpserver "airbnb-clone/profile/internal/adapters/http_server"
+	"airbnb-clone/profile/internal/adapters/publisher"
 	"airbnb-clone/profile/internal/adapters/repository"
+	"airbnb-clone/profile/internal/adapters/signer"
 	"airbnb-clone/profile/internal/config"
+	"airbnb-clone/profile/internal/domain/entity"
 	"airbnb-clone/profile/internal/domain/service"
-
+	"airbnb-clone/shared/authclient"
+	"airbnb-clone/shared/middleware"
+	"airbnb-clone/shared/outbox"
+	"airbnb-clone/shared/storage"
+	"context"
+	"expvar"
 	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,26 +34,101 @@ func main() {
 	log := createLogger(cfg.Env)
 	log.Info("profile app just started")
 
-	profileRepo, err := repository.New(cfg)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := repository.NewPostgresDB(cfg)
 	if err != nil {
 		log.Error("failed to setup database connection")
 		os.Exit(1)
 	}
 
-	profileService := service.NewProfileService(profileRepo, log, "services/profile/uploads")
-	r := setUpHttpServer(log, profileService)
+	profilePublisher := publisher.NewKafkaPublisher(cfg.Kafka.Brokers, cfg.Kafka.ProfileTopic)
+	defer profilePublisher.Close()
+
+	relay := outbox.NewRelay[entity.ProfileEvent](outbox.NewGormStore(db), profilePublisher, outbox.Config{
+		PollInterval:   cfg.Outbox.PollInterval,
+		BatchSize:      cfg.Outbox.BatchSize,
+		PublishTimeout: cfg.Outbox.PublishTimeout,
+		MaxBackoff:     cfg.Outbox.MaxBackoff,
+	}, log)
+	go relay.Run(ctx)
+
+	blobs, err := storage.New(ctx, storage.Config{
+		Backend:   cfg.Storage.Backend,
+		LocalDir:  cfg.Storage.LocalDir,
+		PublicURL: cfg.Storage.PublicURL,
+		S3: storage.S3Config{
+			Endpoint:  cfg.Storage.S3.Endpoint,
+			AccessKey: cfg.Storage.S3.AccessKey,
+			SecretKey: cfg.Storage.S3.SecretKey,
+			Bucket:    cfg.Storage.S3.Bucket,
+			Region:    cfg.Storage.S3.Region,
+			UseSSL:    cfg.Storage.S3.UseSSL,
+		},
+	})
+	if err != nil {
+		log.Error("failed to setup blob storage", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	var urlSigner *signer.URLSigner
+	if cfg.Images.SigningSecret != "" {
+		urlSigner = signer.New(cfg.Images.SigningSecret, cfg.Images.SignedURLTTL)
+	}
+
+	profileService := service.NewProfileService(repository.New(db), log, blobs, urlSigner)
+	jwks := middleware.NewJWKSCache(cfg.Auth.JWKSURL, cfg.Auth.JWKSCacheTTL)
+	validator := middleware.NewTokenValidator(jwks, cfg.Auth.Issuer, cfg.Auth.Audience, cfg.Auth.ClockSkew)
+	if cfg.Auth.GRPC.Address != "" {
+		authClient, err := authclient.New(authclient.Config{
+			Address:    cfg.Auth.GRPC.Address,
+			CAFile:     cfg.Auth.GRPC.CAFile,
+			ServerName: cfg.Auth.GRPC.ServerName,
+			CertFile:   cfg.Auth.GRPC.CertFile,
+			KeyFile:    cfg.Auth.GRPC.KeyFile,
+			Timeout:    cfg.Auth.GRPC.Timeout,
+			CacheTTL:   cfg.Auth.GRPC.CacheTTL,
+			CacheSize:  cfg.Auth.GRPC.CacheSize,
+		})
+		if err != nil {
+			log.Error("failed to setup auth client", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		defer authClient.Close()
+		validator.UseIntrospector(authClient)
+	}
+	if cfg.AdminAddress != "" {
+		go serveAdmin(log, cfg.AdminAddress)
+	}
+
+	r := setUpHttpServer(log, profileService, validator)
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Error("invalid trusted proxies", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 	if err := r.Run(cfg.Address); err != nil {
 		log.Error("Failed to start server:", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
 	}
 }
 
-func setUpHttpServer(log *slog.Logger, profileService service.ProfileService) *gin.Engine {
+func setUpHttpServer(log *slog.Logger, profileService service.ProfileService, validator *middleware.TokenValidator) *gin.Engine {
 	r := gin.Default()
 	profileController := httpserver.NewProfileController(log, profileService)
-	httpserver.SetupProfileRoutes(r, profileController)
+	httpserver.SetupProfileRoutes(r, profileController, validator)
 	return r
 }
 
+// serveAdmin serves the runtime metrics on a listener of its own, so that they
+// are not exposed with the API
+func serveAdmin(log *slog.Logger, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	if err := http.ListenAndServe(address, mux); err != nil {
+		log.Error("admin server stopped", slog.String("error", err.Error()))
+	}
+}
+
 func createLogger(env string) *slog.Logger {
 	var log *slog.Logger
 
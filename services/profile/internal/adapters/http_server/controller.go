@@ -1,12 +1,15 @@
 package httpserver
 
 import (
-	"airbnb-clone/profile/internal/adapters/http_server/middleware"
 	"airbnb-clone/profile/internal/domain/entity"
 	"airbnb-clone/profile/internal/domain/service"
+	"airbnb-clone/shared/middleware"
+	"airbnb-clone/shared/storage"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +20,7 @@ type ProfileController interface {
 	ServeImages(ctx *gin.Context)
 	GetProfile(ctx *gin.Context)
 	DeleteProfile(ctx *gin.Context)
+	RemoveProfile(ctx *gin.Context)
 	UpdateProfile(ctx *gin.Context)
 	GetYourProfile(ctx *gin.Context)
 }
@@ -86,6 +90,10 @@ func (c *profileController) CreateProfile(ctx *gin.Context) {
 
 	profile, err := c.profileService.CreateProfile(request, userID, imageFile)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidImage) || errors.Is(err, service.ErrImageTooLarge) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		log.Error("failed to create a profile", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -170,6 +178,33 @@ func (c *profileController) DeleteProfile(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
 }
 
+// RemoveProfile lets moderators delete the profile of any user
+func (c *profileController) RemoveProfile(ctx *gin.Context) {
+	const fn = "adapters.controller.RemoveProfile"
+	log := c.log.With(
+		slog.String("fn", fn),
+	)
+
+	userID := ctx.Param("id")
+	if userID == "" {
+		log.Error("user id was not provided")
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "User ID was not provided"})
+		return
+	}
+
+	if err := c.profileService.DeleteProfile(userID); err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Profile with provided ID not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Info("profile removed by moderator", slog.String("user_id", userID))
+	ctx.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
 func (c *profileController) UpdateProfile(ctx *gin.Context) {
 	const fn = "adapters.controller.UpdateProfile"
 	log := c.log.With(
@@ -224,6 +259,10 @@ func (c *profileController) UpdateProfile(ctx *gin.Context) {
 
 	profile, err := c.profileService.UpdateProfile(userID, request, imageFile)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidImage) || errors.Is(err, service.ErrImageTooLarge) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		log.Error("failed to update profile", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -233,6 +272,38 @@ func (c *profileController) UpdateProfile(ctx *gin.Context) {
 }
 
 func (c *profileController) ServeImages(ctx *gin.Context) {
-	filename := "services/profile/uploads/" + ctx.Param("filename")
-	ctx.File(filename)
+	const fn = "adapters.controller.ServeImages"
+	log := c.log.With(slog.String("fn", fn))
+
+	name := ctx.Param("filename")
+	if !storage.ValidImageName(name) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image name"})
+		return
+	}
+
+	expires, signature := ctx.Query("expires"), ctx.Query("signature")
+	blob, err := c.profileService.OpenImage(name, expires, signature)
+	if err != nil {
+		if errors.Is(err, service.ErrImageAccessDenied) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Image URL is not signed or has expired"})
+			return
+		}
+		if errors.Is(err, service.ErrImageNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		log.Error("failed to open image", slog.String("error", err.Error()))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer blob.Body.Close()
+
+	// a signed URL may only be cached until it expires
+	cacheControl := storage.ImmutableCacheControl
+	if unix, err := strconv.ParseInt(expires, 10, 64); err == nil && signature != "" {
+		maxAge := max(0, time.Until(time.Unix(unix, 0))/time.Second)
+		cacheControl = fmt.Sprintf("private, max-age=%d, immutable", maxAge)
+	}
+
+	storage.ServeBlob(ctx, blob, cacheControl)
 }
@@ -1,14 +1,14 @@
 package httpserver
 
 import (
-	"airbnb-clone/profile/internal/adapters/http_server/middleware"
+	"airbnb-clone/shared/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupProfileRoutes(r *gin.Engine, profileController ProfileController) {
+func SetupProfileRoutes(r *gin.Engine, profileController ProfileController, validator *middleware.TokenValidator) {
 	authGroup := r.Group("/")
-	authGroup.Use(middleware.AuthMiddleware())
+	authGroup.Use(middleware.AuthMiddleware(validator))
 	{
 		authGroup.POST("/profile", profileController.CreateProfile)
 		authGroup.GET("/user/me", profileController.GetYourProfile)
@@ -16,6 +16,12 @@ func SetupProfileRoutes(r *gin.Engine, profileController ProfileController) {
 		authGroup.PUT("/profile", profileController.UpdateProfile)
 	}
 
+	adminGroup := r.Group("/admin")
+	adminGroup.Use(middleware.AuthMiddleware(validator), middleware.RequirePermission(middleware.PermissionModerateProfiles))
+	{
+		adminGroup.DELETE("/profile/:id", profileController.RemoveProfile)
+	}
+
 	r.GET("/user/:id", profileController.GetProfile)
 	r.GET("/uploads/:filename", profileController.ServeImages)
 }
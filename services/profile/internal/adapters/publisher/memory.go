@@ -0,0 +1,40 @@
+package publisher
+
+import (
+	"airbnb-clone/profile/internal/domain/entity"
+	"context"
+	"sync"
+)
+
+// InMemoryPublisher keeps published events in memory. It is meant for tests
+// and for running the service without a broker
+type InMemoryPublisher struct {
+	mu     sync.Mutex
+	events []entity.ProfileEvent
+}
+
+func NewInMemoryPublisher() *InMemoryPublisher {
+	return &InMemoryPublisher{}
+}
+
+func (p *InMemoryPublisher) Publish(_ context.Context, event *entity.ProfileEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, *event)
+	return nil
+}
+
+// Events returns a copy of everything published so far
+func (p *InMemoryPublisher) Events() []entity.ProfileEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	events := make([]entity.ProfileEvent, len(p.events))
+	copy(events, p.events)
+	return events
+}
+
+func (p *InMemoryPublisher) Close() error {
+	return nil
+}
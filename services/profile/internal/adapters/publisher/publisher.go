@@ -0,0 +1,11 @@
+package publisher
+
+import (
+	"airbnb-clone/profile/internal/domain/entity"
+	"context"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event *entity.ProfileEvent) error
+	Close() error
+}
@@ -18,14 +18,16 @@ type ProfileRepository interface {
 	GetMe(userId string) (*entity.Profile, error)
 	DeleteProfileByID(id string) error
 	UpdateProfileFields(id string, updates map[string]interface{}) error
+	AddOutboxMessage(message *entity.OutboxMessage) error
+	WithinTransaction(fn func(repo ProfileRepository) error) error
 }
 
 type storage struct {
 	db *gorm.DB
 }
 
-func New(cfg *config.Config) (ProfileRepository, error) {
-	const fn = "adapters.repository.New"
+func NewPostgresDB(cfg *config.Config) (*gorm.DB, error) {
+	const fn = "adapters.repository.NewPostgresDB"
 	dsn := fmt.Sprintf("host=%s user=%s "+
 		"password=%s dbname=%s port=%d sslmode=disable",
 		cfg.PostgresConnect.Host, cfg.PostgresConnect.User, cfg.PostgresConnect.Password, cfg.PostgresConnect.DatabaseName, cfg.PostgresConnect.Port)
@@ -37,11 +39,23 @@ func New(cfg *config.Config) (ProfileRepository, error) {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	err = db.AutoMigrate(&entity.Profile{}) // domain models
+	err = db.AutoMigrate(&entity.Profile{}, &entity.OutboxMessage{}) // domain models
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
-	return &storage{db: db}, nil
+	return db, nil
+}
+
+func New(db *gorm.DB) ProfileRepository {
+	return &storage{db: db}
+}
+
+// WithinTransaction runs fn against a repository bound to a single transaction,
+// so profile changes and their outbox messages are committed together
+func (s *storage) WithinTransaction(fn func(repo ProfileRepository) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&storage{db: tx})
+	})
 }
 
 func (s *storage) CreateNewProfile(profile *entity.Profile) error {
@@ -122,3 +136,13 @@ func (s *storage) UpdateProfileFields(id string, updates map[string]interface{})
 
 	return nil
 }
+
+func (s *storage) AddOutboxMessage(message *entity.OutboxMessage) error {
+	const fn = "adapters.repository.AddOutboxMessage"
+
+	result := s.db.Create(message)
+	if result.Error != nil {
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	return nil
+}
@@ -12,12 +12,24 @@ type Config struct {
 	Env             string `yaml:"env" env-default:"local"`
 	HttpServer      `yaml:"http_server"`
 	PostgresConnect `yaml:"postgres_storage"`
+	Auth            `yaml:"auth"`
+	Kafka           `yaml:"kafka"`
+	Outbox          `yaml:"outbox"`
+	Storage         `yaml:"storage"`
+	Images          `yaml:"images"`
 }
 
 type HttpServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// TrustedProxies are the CIDRs of the reverse proxies in front of the
+	// service. X-Forwarded-For is only believed when the request comes from one
+	// of them; empty trusts no proxy and the client IP is the peer address
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" env-separator:","`
+	// AdminAddress serves /debug/vars apart from the API. It must not be
+	// reachable from the public network; empty disables it
+	AdminAddress string `yaml:"admin_address" env-default:"localhost:9090"`
 }
 
 type PostgresConnect struct {
@@ -28,6 +40,67 @@ type PostgresConnect struct {
 	DatabaseName string `yaml:"dbname"  env-required:"true"`
 }
 
+type Kafka struct {
+	Brokers      []string `yaml:"brokers" env-default:"localhost:9092"`
+	ProfileTopic string   `yaml:"profile_topic" env-default:"profile-events"`
+}
+
+type Outbox struct {
+	PollInterval   time.Duration `yaml:"poll_interval" env-default:"1s"`
+	BatchSize      int           `yaml:"batch_size" env-default:"100"`
+	PublishTimeout time.Duration `yaml:"publish_timeout" env-default:"5s"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" env-default:"1m"`
+}
+
+type Storage struct {
+	// Backend is "local" or "s3"
+	Backend   string `yaml:"backend" env-default:"local"`
+	LocalDir  string `yaml:"local_dir" env-default:"services/profile/uploads"`
+	PublicURL string `yaml:"public_url" env-default:"/uploads"`
+	S3        S3     `yaml:"s3"`
+}
+
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" env-default:"localhost:9000"`
+	AccessKey string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"S3_SECRET_KEY"`
+	Bucket    string `yaml:"bucket" env-default:"profile-images"`
+	Region    string `yaml:"region" env-default:"us-east-1"`
+	UseSSL    bool   `yaml:"use_ssl" env-default:"false"`
+}
+
+type Images struct {
+	// SigningSecret turns on signed image URLs: every image URL handed out is
+	// signed and unsigned or expired URLs are rejected. Empty keeps images public
+	SigningSecret string        `yaml:"signing_secret" env:"IMAGE_URL_SECRET"`
+	SignedURLTTL  time.Duration `yaml:"signed_url_ttl" env-default:"15m"`
+}
+
+type Auth struct {
+	JWKSURL      string        `yaml:"jwks_url" env-default:"http://auth-service:8000/.well-known/jwks.json"`
+	JWKSCacheTTL time.Duration `yaml:"jwks_cache_ttl" env-default:"5m"`
+	Issuer       string        `yaml:"issuer" env-default:"airbnb-clone-auth"`
+	Audience     string        `yaml:"audience" env-default:"airbnb-clone"`
+	ClockSkew    time.Duration `yaml:"clock_skew" env-default:"30s"`
+	GRPC         AuthGRPC      `yaml:"grpc"`
+}
+
+// AuthGRPC configures the client of the internal API of the auth service.
+// Once Address is set every token is also introspected there, with answers
+// cached for CacheTTL. TLS is used once CAFile is set, and a client
+// certificate is presented once CertFile and KeyFile are set as well
+type AuthGRPC struct {
+	Address string `yaml:"address"`
+	CAFile  string `yaml:"ca_file" env:"AUTH_GRPC_CA_FILE"`
+	// ServerName overrides the name the server certificate is checked for
+	ServerName string        `yaml:"server_name"`
+	CertFile   string        `yaml:"cert_file" env:"AUTH_GRPC_CERT_FILE"`
+	KeyFile    string        `yaml:"key_file" env:"AUTH_GRPC_KEY_FILE"`
+	Timeout    time.Duration `yaml:"timeout" env-default:"2s"`
+	CacheTTL   time.Duration `yaml:"cache_ttl" env-default:"30s"`
+	CacheSize  int           `yaml:"cache_size" env-default:"10000"`
+}
+
 func MustLoad() *Config {
 	configPath := "config/local.yaml"
 	if configPath == "" {
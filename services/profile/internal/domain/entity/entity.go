@@ -27,17 +27,26 @@ type UpdateProfileRequest struct {
 }
 
 type ProfileResponse struct {
-	ID          string    `json:"id"`
-	PhoneNumber string    `json:"phone_number,omitempty"`
-	Name        string    `json:"name"`
-	Surname     string    `json:"surname"`
-	DateOfBirth time.Time `json:"date_of_birth,omitempty"`
-	ImageURL    string    `json:"image_url"`
+	ID          string     `json:"id"`
+	PhoneNumber string     `json:"phone_number,omitempty"`
+	Name        string     `json:"name"`
+	Surname     string     `json:"surname"`
+	DateOfBirth time.Time  `json:"date_of_birth,omitempty"`
+	ImageURL    string     `json:"image_url"`
+	ImageURLs   *ImageURLs `json:"image_urls,omitempty"`
 }
 
 type PublicProfileResponse struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Surname  string `json:"surname"`
-	ImageURL string `json:"image_url"`
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Surname   string     `json:"surname"`
+	ImageURL  string     `json:"image_url"`
+	ImageURLs *ImageURLs `json:"image_urls,omitempty"`
+}
+
+// ImageURLs holds one URL per rendition produced by the image pipeline
+type ImageURLs struct {
+	Thumbnail string `json:"thumbnail"`
+	Medium    string `json:"medium"`
+	Full      string `json:"full"`
 }
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+const (
+	EventProfileCreated = "profile.created"
+	EventProfileUpdated = "profile.updated"
+	EventProfileDeleted = "profile.deleted"
+
+	ProfileEventVersion = 1
+)
+
+type ProfileEvent struct {
+	EventID    string          `json:"event_id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Profile    ProfileSnapshot `json:"profile"`
+}
+
+type ProfileSnapshot struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Surname  string `json:"surname"`
+	ImageURL string `json:"image_url"`
+}
@@ -3,5 +3,9 @@ package service
 import "errors"
 
 var (
-	ErrProfileNotFound = errors.New("profile with provided ID was not found")
+	ErrProfileNotFound   = errors.New("profile with provided ID was not found")
+	ErrImageNotFound     = errors.New("image not found")
+	ErrImageAccessDenied = errors.New("image url is not signed or has expired")
+	ErrInvalidImage      = errors.New("invalid image file")
+	ErrImageTooLarge     = errors.New("image size too large")
 )
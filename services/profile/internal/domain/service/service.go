@@ -2,15 +2,21 @@ package service
 
 import (
 	"airbnb-clone/profile/internal/adapters/repository"
+	"airbnb-clone/profile/internal/adapters/signer"
 	"airbnb-clone/profile/internal/domain/entity"
+	"airbnb-clone/shared/imaging"
+	"airbnb-clone/shared/storage"
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"mime/multipart"
-	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type ProfileService interface {
@@ -19,21 +25,20 @@ type ProfileService interface {
 	GetProfile(userId string) (*entity.PublicProfileResponse, error)
 	DeleteProfile(userId string) error
 	UpdateProfile(userId string, request *entity.UpdateProfileRequest, imageFile *multipart.FileHeader) (*entity.ProfileResponse, error)
+	OpenImage(name string, expires string, signature string) (*storage.Blob, error)
 }
 
 type profileService struct {
 	profileRepository repository.ProfileRepository
 	log               *slog.Logger
-	uploadDir         string
+	blobs             storage.BlobStore
+	// urlSigner signs image URLs and is required to open images. When nil
+	// images are public
+	urlSigner *signer.URLSigner
 }
 
-func NewProfileService(profileRepo repository.ProfileRepository, logger *slog.Logger, uploadDir string) ProfileService {
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		logger.Error("Failed to create upload directory: %v", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
-		os.Exit(1)
-	}
-
-	return &profileService{profileRepository: profileRepo, log: logger, uploadDir: uploadDir}
+func NewProfileService(profileRepo repository.ProfileRepository, logger *slog.Logger, blobs storage.BlobStore, urlSigner *signer.URLSigner) ProfileService {
+	return &profileService{profileRepository: profileRepo, log: logger, blobs: blobs, urlSigner: urlSigner}
 }
 
 func (s *profileService) CreateProfile(request *entity.CreateProfileRequest, userId string, imageFile *multipart.FileHeader) (*entity.ProfileResponse, error) {
@@ -57,8 +62,14 @@ func (s *profileService) CreateProfile(request *entity.CreateProfileRequest, use
 		ImagePath:   imagePath,
 	}
 
-	if err := s.profileRepository.CreateNewProfile(profile); err != nil {
-		os.Remove(imagePath)
+	err = s.profileRepository.WithinTransaction(func(repo repository.ProfileRepository) error {
+		if err := repo.CreateNewProfile(profile); err != nil {
+			return err
+		}
+		return enqueueEvent(repo, entity.EventProfileCreated, profile)
+	})
+	if err != nil {
+		s.removeImageFile(imagePath)
 		log.Error("failed to create a new profile", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
 		return nil, fmt.Errorf("error creating profile: %w", err)
 	}
@@ -69,7 +80,8 @@ func (s *profileService) CreateProfile(request *entity.CreateProfileRequest, use
 		Name:        profile.Name,
 		Surname:     profile.Surname,
 		DateOfBirth: profile.DateOfBirth,
-		ImageURL:    "/uploads/" + filepath.Base(imagePath),
+		ImageURL:    s.signedImageURL(imagePath, imaging.Full),
+		ImageURLs:   s.toImageURLs(imagePath),
 	}, nil
 }
 
@@ -94,7 +106,8 @@ func (s *profileService) GetYourProfile(userId string) (*entity.ProfileResponse,
 		Name:        profile.Name,
 		Surname:     profile.Surname,
 		DateOfBirth: profile.DateOfBirth,
-		ImageURL:    "/uploads/" + filepath.Base(profile.ImagePath),
+		ImageURL:    s.signedImageURL(profile.ImagePath, imaging.Full),
+		ImageURLs:   s.toImageURLs(profile.ImagePath),
 	}, nil
 
 }
@@ -115,9 +128,10 @@ func (s *profileService) GetProfile(userId string) (*entity.PublicProfileRespons
 	}
 
 	return &entity.PublicProfileResponse{
-		ID:       profile.ID,
-		Name:     profile.Name,
-		ImageURL: "/uploads/" + filepath.Base(profile.ImagePath),
+		ID:        profile.ID,
+		Name:      profile.Name,
+		ImageURL:  s.signedImageURL(profile.ImagePath, imaging.Full),
+		ImageURLs: s.toImageURLs(profile.ImagePath),
 	}, nil
 }
 
@@ -127,7 +141,13 @@ func (s *profileService) DeleteProfile(userId string) error {
 		slog.String("fn", fn),
 	)
 
-	if err := s.profileRepository.DeleteProfileByID(userId); err != nil {
+	err := s.profileRepository.WithinTransaction(func(repo repository.ProfileRepository) error {
+		if err := repo.DeleteProfileByID(userId); err != nil {
+			return err
+		}
+		return enqueueEvent(repo, entity.EventProfileDeleted, &entity.Profile{ID: userId})
+	})
+	if err != nil {
 		if errors.Is(err, repository.ErrProfileNotFound) {
 			log.Error("profile not found", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
 			return ErrProfileNotFound
@@ -162,7 +182,8 @@ func (s *profileService) UpdateProfile(userId string, request *entity.UpdateProf
 
 	var newImagePath string
 	if imageFile != nil {
-		newImagePath, err := s.saveImage(imageFile, userId)
+		var err error
+		newImagePath, err = s.saveImage(imageFile, userId)
 		if err != nil {
 			log.Error("failed to save new image", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
 			return nil, err
@@ -170,19 +191,25 @@ func (s *profileService) UpdateProfile(userId string, request *entity.UpdateProf
 		updates["image_path"] = newImagePath
 	}
 
-	if len(updates) > 0 {
-		if err := s.profileRepository.UpdateProfileFields(userId, updates); err != nil {
-			if newImagePath != "" {
-				os.Remove(newImagePath)
+	var updatedProfile *entity.Profile
+	err := s.profileRepository.WithinTransaction(func(repo repository.ProfileRepository) error {
+		if len(updates) > 0 {
+			if err := repo.UpdateProfileFields(userId, updates); err != nil {
+				log.Error("failed to update profile", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+				return err
 			}
-			log.Error("failed to update profile", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
-			return nil, err
 		}
-	}
 
-	updatedProfile, err := s.profileRepository.GetMe(userId)
+		var err error
+		updatedProfile, err = repo.GetMe(userId)
+		if err != nil {
+			log.Error("failed to get updated profile", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+			return err
+		}
+		return enqueueEvent(repo, entity.EventProfileUpdated, updatedProfile)
+	})
 	if err != nil {
-		log.Error("failed to get updated profile", slog.Attr{Key: "error", Value: slog.StringValue(err.Error())})
+		s.removeImageFile(newImagePath)
 		return nil, err
 	}
 
@@ -192,26 +219,51 @@ func (s *profileService) UpdateProfile(userId string, request *entity.UpdateProf
 		Name:        updatedProfile.Name,
 		Surname:     updatedProfile.Surname,
 		DateOfBirth: updatedProfile.DateOfBirth,
-		ImageURL:    "/uploads/" + filepath.Base(updatedProfile.ImagePath),
+		ImageURL:    s.signedImageURL(updatedProfile.ImagePath, imaging.Full),
+		ImageURLs:   s.toImageURLs(updatedProfile.ImagePath),
 	}, nil
 }
 
-func (s *profileService) saveImage(file *multipart.FileHeader, userID string) (string, error) {
-	if file.Size > 5*1024*1024 { // 5MB limit
-		return "", errors.New("image size too large")
+// enqueueEvent stores the event in the outbox within the caller's transaction.
+// The outbox relay publishes it once the transaction is committed
+func enqueueEvent(repo repository.ProfileRepository, eventType string, profile *entity.Profile) error {
+	event := &entity.ProfileEvent{
+		EventID:    uuid.New().String(),
+		Type:       eventType,
+		Version:    entity.ProfileEventVersion,
+		OccurredAt: time.Now().UTC(),
+		Profile: entity.ProfileSnapshot{
+			ID:      profile.ID,
+			Name:    profile.Name,
+			Surname: profile.Surname,
+		},
 	}
+	event.Profile.ImageURL = imageURL(profile.ImagePath, imaging.Full)
 
-	allowedTypes := map[string]bool{ // validate MIME type
-		"image/jpeg": true,
-		"image/png":  true,
-	}
-	if !allowedTypes[file.Header.Get("Content-Type")] {
-		return "", errors.New("invalid image format")
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
 	}
 
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("%s_%d%s", userID, time.Now().Unix(), ext)
-	filePath := filepath.Join(s.uploadDir, filename)
+	return repo.AddOutboxMessage(&entity.OutboxMessage{
+		ID:            event.EventID,
+		AggregateID:   profile.ID,
+		EventType:     eventType,
+		Payload:       payload,
+		NextAttemptAt: event.OccurredAt,
+	})
+}
+
+// saveImage runs the upload through the image pipeline and stores every
+// rendition. It returns the key of the full-size rendition, or an empty key
+// when no image was uploaded
+func (s *profileService) saveImage(file *multipart.FileHeader, userID string) (string, error) {
+	if file == nil {
+		return "", nil
+	}
+	if file.Size > 5*1024*1024 { // 5MB limit
+		return "", ErrImageTooLarge
+	}
 
 	src, err := file.Open()
 	if err != nil {
@@ -219,15 +271,93 @@ func (s *profileService) saveImage(file *multipart.FileHeader, userID string) (s
 	}
 	defer src.Close()
 
-	dst, err := os.Create(filePath)
+	variants, err := imaging.Process(src)
 	if err != nil {
+		if errors.Is(err, imaging.ErrUnsupportedFormat) {
+			return "", ErrInvalidImage
+		}
+		if errors.Is(err, imaging.ErrImageTooLarge) {
+			return "", ErrImageTooLarge
+		}
 		return "", err
 	}
-	defer dst.Close()
 
-	if _, err = io.Copy(dst, src); err != nil {
-		return "", err
+	base := fmt.Sprintf("%s_%d", userID, time.Now().Unix())
+	var written []string
+	for _, variant := range variants {
+		key := imaging.FileName(base, variant.Name)
+		err := s.blobs.Put(context.Background(), key, bytes.NewReader(variant.Data), int64(len(variant.Data)), imaging.ContentType)
+		if err != nil {
+			for _, k := range written {
+				s.blobs.Delete(context.Background(), k)
+			}
+			return "", err
+		}
+		written = append(written, key)
+	}
+
+	return imaging.FileName(base, imaging.Full), nil
+}
+
+// OpenImage opens a stored rendition by the file name used in image URLs.
+// When URL signing is on, expires and signature must come from a valid URL
+func (s *profileService) OpenImage(name string, expires string, signature string) (*storage.Blob, error) {
+	const fn = "domain.service.OpenImage"
+
+	if s.urlSigner != nil {
+		if _, err := s.urlSigner.Verify(imageURL(name, imaging.Full), expires, signature, time.Now()); err != nil {
+			return nil, fmt.Errorf("%s: %w: %w", fn, ErrImageAccessDenied, err)
+		}
+	}
+
+	blob, err := s.blobs.Get(context.Background(), name)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			return nil, ErrImageNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return blob, nil
+}
+
+// removeImageFile deletes every rendition of an image. Images uploaded before
+// the blob store existed are stored with their full path, hence filepath.Base
+func (s *profileService) removeImageFile(path string) {
+	if path == "" {
+		return
+	}
+	for _, p := range imaging.VariantPaths(path) {
+		if err := s.blobs.Delete(context.Background(), filepath.Base(p)); err != nil {
+			s.log.Error("failed to delete image blob", slog.String("key", p), slog.String("error", err.Error()))
+		}
 	}
+}
 
-	return filePath, nil
+func imageURL(path string, rendition string) string {
+	if path == "" {
+		return ""
+	}
+	return "/uploads/" + filepath.Base(imaging.VariantPath(path, rendition))
+}
+
+// signedImageURL is the URL handed to clients, signed when URL signing is on.
+// Events carry the unsigned URL since they outlive any signature
+func (s *profileService) signedImageURL(path string, rendition string) string {
+	url := imageURL(path, rendition)
+	if url == "" || s.urlSigner == nil {
+		return url
+	}
+	return s.urlSigner.Sign(url, time.Now())
+}
+
+func (s *profileService) toImageURLs(path string) *entity.ImageURLs {
+	if path == "" {
+		return nil
+	}
+	return &entity.ImageURLs{
+		Thumbnail: s.signedImageURL(path, imaging.Thumbnail),
+		Medium:    s.signedImageURL(path, imaging.Medium),
+		Full:      s.signedImageURL(path, imaging.Full),
+	}
 }
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"airbnb-clone/profile/internal/adapters/publisher"
+	"airbnb-clone/profile/internal/adapters/repository"
+	"airbnb-clone/profile/internal/domain/entity"
+	"airbnb-clone/shared/outbox"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// fakeRepository keeps profiles and outbox messages in memory. Only the
+// methods used by create, update and delete are implemented
+type fakeRepository struct {
+	repository.ProfileRepository
+	profiles map[string]*entity.Profile
+	outbox   []entity.OutboxMessage
+}
+
+func (r *fakeRepository) CreateNewProfile(profile *entity.Profile) error {
+	copied := *profile
+	r.profiles[profile.ID] = &copied
+	return nil
+}
+
+func (r *fakeRepository) GetMe(userId string) (*entity.Profile, error) {
+	profile, ok := r.profiles[userId]
+	if !ok {
+		return nil, repository.ErrProfileNotFound
+	}
+	copied := *profile
+	return &copied, nil
+}
+
+func (r *fakeRepository) UpdateProfileFields(id string, updates map[string]interface{}) error {
+	if name, ok := updates["name"].(string); ok {
+		r.profiles[id].Name = name
+	}
+	return nil
+}
+
+func (r *fakeRepository) DeleteProfileByID(id string) error {
+	if _, ok := r.profiles[id]; !ok {
+		return repository.ErrProfileNotFound
+	}
+	delete(r.profiles, id)
+	return nil
+}
+
+func (r *fakeRepository) AddOutboxMessage(message *entity.OutboxMessage) error {
+	r.outbox = append(r.outbox, *message)
+	return nil
+}
+
+func (r *fakeRepository) WithinTransaction(fn func(repo repository.ProfileRepository) error) error {
+	return fn(r)
+}
+
+// fakeOutboxStore hands the messages of a fakeRepository to the relay
+type fakeOutboxStore struct {
+	repo *fakeRepository
+}
+
+func (s *fakeOutboxStore) FetchPendingOutbox(limit int, _ time.Time) ([]outbox.Message, error) {
+	var messages []outbox.Message
+	for _, msg := range s.repo.outbox {
+		if msg.PublishedAt == nil && len(messages) < limit {
+			messages = append(messages, outbox.Message{ID: msg.ID, AggregateID: msg.AggregateID, Payload: msg.Payload})
+		}
+	}
+	return messages, nil
+}
+
+func (s *fakeOutboxStore) MarkOutboxPublished(id string) error {
+	now := time.Now()
+	for i := range s.repo.outbox {
+		if s.repo.outbox[i].ID == id {
+			s.repo.outbox[i].PublishedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *fakeOutboxStore) MarkOutboxFailed(string, string, time.Time) error { return nil }
+
+func (s *fakeOutboxStore) MarkOutboxDead(string, string) error { return nil }
+
+func (s *fakeOutboxStore) GetOutboxStats() (*outbox.Stats, error) { return &outbox.Stats{}, nil }
+
+func TestProfileChangesPublishEvents(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := &fakeRepository{profiles: map[string]*entity.Profile{}}
+	svc := NewProfileService(repo, log, nil, nil)
+
+	const userID = "user-1"
+	if _, err := svc.CreateProfile(&entity.CreateProfileRequest{PhoneNumber: "+351000000", Name: "Ana", Surname: "Silva"}, userID, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	name := "Anna"
+	if _, err := svc.UpdateProfile(userID, &entity.UpdateProfileRequest{Name: &name}, nil); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := svc.DeleteProfile(userID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	// Run drains the outbox once before it looks at the context
+	pub := publisher.NewInMemoryPublisher()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	outbox.NewRelay[entity.ProfileEvent](&fakeOutboxStore{repo: repo}, pub, outbox.Config{
+		PollInterval:   time.Second,
+		BatchSize:      10,
+		PublishTimeout: time.Second,
+		MaxBackoff:     time.Second,
+	}, log).Run(ctx)
+
+	want := []struct {
+		eventType string
+		name      string
+	}{
+		{entity.EventProfileCreated, "Ana"},
+		{entity.EventProfileUpdated, "Anna"},
+		{entity.EventProfileDeleted, ""},
+	}
+	events := pub.Events()
+	if len(events) != len(want) {
+		t.Fatalf("published %d events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.Type != want[i].eventType || event.Profile.ID != userID || event.Profile.Name != want[i].name {
+			t.Errorf("event %d = %s %s %q, want %s %s %q", i, event.Type, event.Profile.ID, event.Profile.Name,
+				want[i].eventType, userID, want[i].name)
+		}
+		if event.Version != entity.ProfileEventVersion {
+			t.Errorf("event %d has version %d", i, event.Version)
+		}
+	}
+	for _, msg := range repo.outbox {
+		if msg.PublishedAt == nil {
+			t.Errorf("outbox message %s (%s) was not marked as published", msg.ID, msg.EventType)
+		}
+	}
+}
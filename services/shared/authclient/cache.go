@@ -0,0 +1,59 @@
+package authclient
+
+import (
+	"sync"
+	"time"
+)
+
+// cache is a map with per entry expiry, bounded to maxSize entries
+type cache[V any] struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry[V]
+	maxSize int
+}
+
+type cacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func newCache[V any](maxSize int) *cache[V] {
+	return &cache[V]{entries: make(map[string]cacheEntry[V]), maxSize: maxSize}
+}
+
+func (c *cache[V]) get(key string, now time.Time) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+func (c *cache[V]) set(key string, value V, expiresAt time.Time, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxSize {
+		c.evict(now)
+	}
+	c.entries[key] = cacheEntry[V]{value: value, expiresAt: expiresAt}
+}
+
+// evict drops the expired entries, and random ones if that is not enough
+func (c *cache[V]) evict(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < c.maxSize {
+			return
+		}
+		delete(c.entries, key)
+	}
+}
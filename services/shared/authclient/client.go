@@ -0,0 +1,223 @@
+package authclient
+
+import (
+	"airbnb-clone/shared/authv1"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatchUsers is the most ids the auth service takes per BatchGetUsers call
+const maxBatchUsers = 100
+
+var ErrUserNotFound = errors.New("user not found")
+
+// Config of the client. TLS is used once CAFile is set, and a client
+// certificate is presented once CertFile and KeyFile are set as well
+type Config struct {
+	Address string
+	CAFile  string
+	// ServerName overrides the name the server certificate is checked for
+	ServerName string
+	CertFile   string
+	KeyFile    string
+	Timeout    time.Duration
+	CacheTTL   time.Duration
+	CacheSize  int
+}
+
+// Client calls the internal API of the auth service. Answers are cached for
+// the configured TTL, so a token revoked in the meantime is accepted for at
+// most that long
+type Client struct {
+	conn     *grpc.ClientConn
+	api      authv1.AuthServiceClient
+	timeout  time.Duration
+	cacheTTL time.Duration
+	tokens   *cache[*authv1.IntrospectTokenResponse]
+	users    *cache[*authv1.User]
+}
+
+func New(cfg Config) (*Client, error) {
+	const fn = "authclient.New"
+
+	creds, err := clientCredentials(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	conn, err := grpc.NewClient(cfg.Address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return &Client{
+		conn:     conn,
+		api:      authv1.NewAuthServiceClient(conn),
+		timeout:  cfg.Timeout,
+		cacheTTL: cfg.CacheTTL,
+		tokens:   newCache[*authv1.IntrospectTokenResponse](cfg.CacheSize),
+		users:    newCache[*authv1.User](cfg.CacheSize),
+	}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// IntrospectToken asks the auth service whether an access token is still
+// active
+func (c *Client) IntrospectToken(ctx context.Context, token string) (*authv1.IntrospectTokenResponse, error) {
+	const fn = "authclient.IntrospectToken"
+
+	key := tokenKey(token)
+	now := time.Now()
+	if introspection, ok := c.tokens.get(key, now); ok {
+		return introspection, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	introspection, err := c.api.IntrospectToken(ctx, &authv1.IntrospectTokenRequest{Token: token})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	// an active answer must not outlive the token
+	expiresAt := now.Add(c.cacheTTL)
+	if introspection.GetActive() && introspection.GetExpiresAt().AsTime().Before(expiresAt) {
+		expiresAt = introspection.GetExpiresAt().AsTime()
+	}
+	c.tokens.set(key, introspection, expiresAt, now)
+
+	return introspection, nil
+}
+
+// TokenActive lets the HTTP middleware introspect tokens
+func (c *Client) TokenActive(ctx context.Context, token string) (bool, error) {
+	introspection, err := c.IntrospectToken(ctx, token)
+	if err != nil {
+		return false, err
+	}
+	return introspection.GetActive(), nil
+}
+
+// GetUser returns a user of the auth service. Error can be ErrUserNotFound
+func (c *Client) GetUser(ctx context.Context, userID string) (*authv1.User, error) {
+	const fn = "authclient.GetUser"
+
+	now := time.Now()
+	if user, ok := c.users.get(userID, now); ok {
+		return user, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	response, err := c.api.GetUser(ctx, &authv1.GetUserRequest{UserId: userID})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	c.users.set(userID, response.GetUser(), now.Add(c.cacheTTL), now)
+	return response.GetUser(), nil
+}
+
+// BatchGetUsers returns the users that exist by id. Ids that are not cached
+// are fetched in batches the auth service accepts
+func (c *Client) BatchGetUsers(ctx context.Context, userIDs []string) (map[string]*authv1.User, error) {
+	const fn = "authclient.BatchGetUsers"
+
+	now := time.Now()
+	users := make(map[string]*authv1.User, len(userIDs))
+	var uncached []string
+	for _, id := range userIDs {
+		if _, ok := users[id]; ok {
+			continue
+		}
+		if user, ok := c.users.get(id, now); ok {
+			users[id] = user
+			continue
+		}
+		users[id] = nil
+		uncached = append(uncached, id)
+	}
+
+	for start := 0; start < len(uncached); start += maxBatchUsers {
+		batch := uncached[start:min(start+maxBatchUsers, len(uncached))]
+
+		fetchCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		response, err := c.api.BatchGetUsers(fetchCtx, &authv1.BatchGetUsersRequest{UserIds: batch})
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+
+		for _, user := range response.GetUsers() {
+			users[user.GetId()] = user
+			c.users.set(user.GetId(), user, now.Add(c.cacheTTL), now)
+		}
+	}
+
+	// ids marked while fetching are the missing ones
+	for id, user := range users {
+		if user == nil {
+			delete(users, id)
+		}
+	}
+
+	return users, nil
+}
+
+// tokenKey keeps raw tokens out of the cache keys
+func tokenKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// clientCredentials is plaintext without a CA, TLS with one, and mutual TLS
+// once a client certificate is configured as well
+func clientCredentials(cfg Config) (credentials.TransportCredentials, error) {
+	if cfg.CAFile == "" {
+		if cfg.CertFile != "" || cfg.KeyFile != "" {
+			return nil, errors.New("client certificate is set without a ca")
+		}
+		return insecure.NewCredentials(), nil
+	}
+
+	caPEM, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates in %s", cfg.CAFile)
+	}
+	tlsConfig := &tls.Config{RootCAs: rootCAs, ServerName: cfg.ServerName, MinVersion: tls.VersionTLS12}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
@@ -0,0 +1,131 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+)
+
+const (
+	Thumbnail = "thumbnail"
+	Medium    = "medium"
+	Full      = "full"
+
+	// maxPixels guards against decompression bombs: a small file that
+	// declares huge dimensions
+	maxPixels = 40_000_000
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageTooLarge     = errors.New("image dimensions too large")
+)
+
+type Rendition struct {
+	Name    string
+	MaxSide int
+}
+
+// Renditions lists the sizes generated for every upload. Images are scaled
+// down so that their longest side fits MaxSide and never scaled up
+var Renditions = []Rendition{
+	{Name: Thumbnail, MaxSide: 320},
+	{Name: Medium, MaxSide: 1024},
+	{Name: Full, MaxSide: 2048},
+}
+
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Process decodes a JPEG or PNG, applies its EXIF orientation and encodes every
+// rendition as WebP. Re-encoding from pixels drops all metadata, EXIF and GPS
+// included. The format is detected from the bytes, not the declared type
+func Process(r io.Reader) ([]Variant, error) {
+	const fn = "adapters.imaging.Process"
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrUnsupportedFormat
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	var img image.Image
+	switch format {
+	case "jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err == nil {
+			img = applyOrientation(img, jpegOrientation(data))
+		}
+	case "png":
+		img, err = png.Decode(bytes.NewReader(data))
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	variants := make([]Variant, 0, len(Renditions))
+	for _, rendition := range Renditions {
+		scaled := resize(img, rendition.MaxSide)
+
+		var buf bytes.Buffer
+		if err := nativewebp.Encode(&buf, scaled, nil); err != nil {
+			return nil, fmt.Errorf("%s: encode %s: %w", fn, rendition.Name, err)
+		}
+
+		bounds := scaled.Bounds()
+		variants = append(variants, Variant{
+			Name:   rendition.Name,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+			Data:   buf.Bytes(),
+		})
+	}
+
+	return variants, nil
+}
+
+func resize(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	if w <= maxSide && h <= maxSide {
+		dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+		return dst
+	}
+
+	if w >= h {
+		h = max(1, h*maxSide/w)
+		w = maxSide
+	} else {
+		w = max(1, w*maxSide/h)
+		h = maxSide
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
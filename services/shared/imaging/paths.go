@@ -0,0 +1,38 @@
+package imaging
+
+import "strings"
+
+const (
+	// ContentType is the type of every rendition
+	ContentType = "image/webp"
+
+	fullSuffix = "_" + Full + ".webp"
+)
+
+// FileName is the name a rendition of the upload identified by base is stored under
+func FileName(base string, rendition string) string {
+	return base + "_" + rendition + ".webp"
+}
+
+// VariantPath derives the path of a rendition from the path of the full-size
+// one. Uploads stored before the pipeline existed have a single file, which is
+// returned for every rendition
+func VariantPath(fullPath string, rendition string) string {
+	if !strings.HasSuffix(fullPath, fullSuffix) {
+		return fullPath
+	}
+	return strings.TrimSuffix(fullPath, fullSuffix) + "_" + rendition + ".webp"
+}
+
+// VariantPaths returns the paths of every rendition, without duplicates
+func VariantPaths(fullPath string) []string {
+	if !strings.HasSuffix(fullPath, fullSuffix) {
+		return []string{fullPath}
+	}
+
+	paths := make([]string, 0, len(Renditions))
+	for _, rendition := range Renditions {
+		paths = append(paths, VariantPath(fullPath, rendition.Name))
+	}
+	return paths
+}
@@ -0,0 +1,179 @@
+// Package kafkaconsumer is the retry and dead-letter loop the services consume
+// their Kafka topics with
+package kafkaconsumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrPermanent marks failures no retry can fix, e.g. a message that cannot be
+// decoded. Such messages are parked in the dead-letter topic right away
+var ErrPermanent = errors.New("permanent failure")
+
+// Permanent wraps err with ErrPermanent
+func Permanent(err error) error {
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
+
+// Handler applies a message. It must be idempotent, messages are delivered at
+// least once
+type Handler func(ctx context.Context, msg kafka.Message) error
+
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type Config struct {
+	// MaxRetries is the number of times a message is handled before it is
+	// parked, with the wait starting at RetryBackoff and doubling each time.
+	// A message is always handled at least once
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// MaxBackoff caps the wait between attempts to apply or park a message
+	// while the dead-letter topic cannot be written
+	MaxBackoff time.Duration
+}
+
+type Consumer struct {
+	reader     Reader
+	deadLetter Writer
+	handle     Handler
+	cfg        Config
+	log        *slog.Logger
+}
+
+func New(reader Reader, deadLetter Writer, handle Handler, cfg Config, log *slog.Logger) *Consumer {
+	return &Consumer{reader: reader, deadLetter: deadLetter, handle: handle, cfg: cfg, log: log}
+}
+
+// Run reads messages until ctx is cancelled. An offset is committed only after
+// the message was applied or parked in the dead-letter topic, and the next
+// message is not fetched before that, so a later commit can never skip it
+func (c *Consumer) Run(ctx context.Context) {
+	const fn = "kafkaconsumer.Run"
+	log := c.log.With(slog.String("fn", fn))
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Error("failed to fetch message", slog.String("error", err.Error()))
+			if !sleep(ctx, c.cfg.RetryBackoff) {
+				return
+			}
+			continue
+		}
+
+		if err := c.process(ctx, msg); err != nil {
+			return
+		}
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil {
+			// the commit of a later offset covers this one
+			log.Error("failed to commit offset", slog.String("error", err.Error()), slog.Int64("offset", msg.Offset))
+		}
+	}
+}
+
+func (c *Consumer) Close() error {
+	return errors.Join(c.reader.Close(), c.deadLetter.Close())
+}
+
+// process keeps trying to apply or park the message until one of them
+// succeeds. It only fails once ctx is cancelled
+func (c *Consumer) process(ctx context.Context, msg kafka.Message) error {
+	const fn = "kafkaconsumer.process"
+
+	backoff := c.cfg.RetryBackoff
+	for {
+		err := c.applyOrPark(ctx, msg)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		c.log.Error("failed to apply or park message, retrying", slog.String("fn", fn), slog.String("error", err.Error()),
+			slog.Int64("offset", msg.Offset), slog.Int("partition", msg.Partition))
+		if !sleep(ctx, backoff) {
+			return ctx.Err()
+		}
+		backoff = min(backoff*2, c.cfg.MaxBackoff)
+	}
+}
+
+// applyOrPark handles the message with exponential backoff and moves it to the
+// dead-letter topic when it fails permanently or retries are exhausted
+func (c *Consumer) applyOrPark(ctx context.Context, msg kafka.Message) error {
+	attempts := max(c.cfg.MaxRetries, 1)
+	backoff := c.cfg.RetryBackoff
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = c.handle(ctx, msg); err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrPermanent) {
+			return c.sendToDeadLetter(ctx, msg, err, attempt)
+		}
+
+		c.log.Warn("failed to handle message, retrying", slog.String("error", err.Error()),
+			slog.Int("attempt", attempt), slog.Int64("offset", msg.Offset))
+		if !sleep(ctx, backoff) {
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+
+	return c.sendToDeadLetter(ctx, msg, err, attempts)
+}
+
+func (c *Consumer) sendToDeadLetter(ctx context.Context, msg kafka.Message, cause error, attempts int) error {
+	const fn = "kafkaconsumer.sendToDeadLetter"
+
+	c.log.Error("moving message to dead-letter topic", slog.String("fn", fn),
+		slog.String("error", cause.Error()), slog.Int64("offset", msg.Offset))
+
+	headers := make([]kafka.Header, 0, len(msg.Headers)+3)
+	headers = append(headers, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: "dlq-error", Value: []byte(cause.Error())},
+		kafka.Header{Key: "dlq-attempts", Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: "dlq-source", Value: []byte(fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset))},
+	)
+
+	err := c.deadLetter.WriteMessages(ctx, kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers})
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,173 @@
+package kafkaconsumer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeReader serves msgs in order and records what happened, in order
+type fakeReader struct {
+	mu     sync.Mutex
+	msgs   []kafka.Message
+	next   int
+	events []string
+	done   context.CancelFunc
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next == len(r.msgs) {
+		r.done()
+		return kafka.Message{}, ctx.Err()
+	}
+	msg := r.msgs[r.next]
+	r.next++
+	r.events = append(r.events, "fetch "+string(msg.Value))
+	return msg, nil
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, msg := range msgs {
+		r.events = append(r.events, "commit "+string(msg.Value))
+	}
+	return nil
+}
+
+func (r *fakeReader) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+// fakeWriter fails the first failures writes
+type fakeWriter struct {
+	failures int
+	reader   *fakeReader
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	if w.failures > 0 {
+		w.failures--
+		return errors.New("broker unavailable")
+	}
+	for _, msg := range msgs {
+		w.reader.record("park " + string(msg.Value))
+	}
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+func runConsumer(t *testing.T, reader *fakeReader, writer *fakeWriter, handle Handler) []string {
+	t.Helper()
+
+	cfg := Config{MaxRetries: 2, RetryBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+	return runConsumerWithConfig(t, cfg, reader, writer, handle)
+}
+
+func runConsumerWithConfig(t *testing.T, cfg Config, reader *fakeReader, writer *fakeWriter, handle Handler) []string {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reader.done = cancel
+
+	New(reader, writer, handle, cfg, slog.New(slog.NewTextHandler(io.Discard, nil))).Run(ctx)
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Fatal("consumer did not drain the messages in time")
+	}
+	return reader.events
+}
+
+func assertEvents(t *testing.T, got []string, want []string) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("events = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestRunParksFailedMessageBeforeFetchingNext(t *testing.T) {
+	reader := &fakeReader{msgs: []kafka.Message{{Value: []byte("a")}, {Value: []byte("b")}}}
+	writer := &fakeWriter{failures: 3, reader: reader}
+
+	events := runConsumer(t, reader, writer, func(_ context.Context, msg kafka.Message) error {
+		if string(msg.Value) == "a" {
+			return errors.New("database unavailable")
+		}
+		reader.record("apply " + string(msg.Value))
+		return nil
+	})
+
+	assertEvents(t, events, []string{"fetch a", "park a", "commit a", "fetch b", "apply b", "commit b"})
+}
+
+func TestRunRetriesTransientFailures(t *testing.T) {
+	reader := &fakeReader{msgs: []kafka.Message{{Value: []byte("a")}}}
+	writer := &fakeWriter{reader: reader}
+
+	failures := 1
+	events := runConsumer(t, reader, writer, func(_ context.Context, msg kafka.Message) error {
+		if failures > 0 {
+			failures--
+			return errors.New("database unavailable")
+		}
+		reader.record("apply " + string(msg.Value))
+		return nil
+	})
+
+	assertEvents(t, events, []string{"fetch a", "apply a", "commit a"})
+}
+
+func TestRunParksPermanentFailuresRightAway(t *testing.T) {
+	reader := &fakeReader{msgs: []kafka.Message{{Value: []byte("a")}}}
+	writer := &fakeWriter{reader: reader}
+
+	attempts := 0
+	events := runConsumer(t, reader, writer, func(context.Context, kafka.Message) error {
+		attempts++
+		return Permanent(errors.New("malformed json"))
+	})
+
+	if attempts != 1 {
+		t.Fatalf("handled %d times, want 1", attempts)
+	}
+	assertEvents(t, events, []string{"fetch a", "park a", "commit a"})
+}
+
+func TestRunHandlesOnceWithoutRetries(t *testing.T) {
+	reader := &fakeReader{msgs: []kafka.Message{{Value: []byte("a")}}}
+	writer := &fakeWriter{reader: reader}
+
+	attempts := 0
+	cfg := Config{RetryBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+	events := runConsumerWithConfig(t, cfg, reader, writer, func(context.Context, kafka.Message) error {
+		attempts++
+		return errors.New("database unavailable")
+	})
+
+	if attempts != 1 {
+		t.Fatalf("handled %d times, want 1", attempts)
+	}
+	assertEvents(t, events, []string{"fetch a", "park a", "commit a"})
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	UserIDKey        = "userID"
+	EmailVerifiedKey = "emailVerified"
+	AuthMethodsKey   = "authMethods"
+	RolesKey         = "roles"
+	PermissionsKey   = "permissions"
+)
+
+func AuthMiddleware(validator *TokenValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			c.Abort()
+			return
+		}
+
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
+			c.Abort()
+			return
+		}
+
+		claims, err := validator.Validate(c.Request.Context(), parts[1])
+		if errors.Is(err, ErrIntrospection) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication is temporarily unavailable"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token", "reason": tokenErrorReason(err)})
+			c.Abort()
+			return
+		}
+
+		c.Set(UserIDKey, claims.UserID)
+		c.Set(EmailVerifiedKey, claims.EmailVerified)
+		c.Set(AuthMethodsKey, claims.AuthMethods)
+		c.Set(RolesKey, claims.Roles)
+		c.Set(PermissionsKey, claims.Permissions)
+		c.Next()
+	}
+}
+
+// tokenErrorReason tells clients why a token was rejected without echoing
+// parser internals, e.g. so that they refresh an expired token
+func tokenErrorReason(err error) string {
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		return "token_expired"
+	case errors.Is(err, ErrTokenNotYetValid):
+		return "token_not_yet_valid"
+	case errors.Is(err, ErrTokenWrongType):
+		return "wrong_token_type"
+	case errors.Is(err, ErrTokenRevoked):
+		return "token_revoked"
+	case errors.Is(err, ErrTokenInvalidIssuer), errors.Is(err, ErrTokenInvalidAudience):
+		return "token_not_accepted"
+	case errors.Is(err, ErrTokenMissingClaim):
+		return "missing_claim"
+	default:
+		return "invalid_token"
+	}
+}
+
+func GetUserIDFromContext(c *gin.Context) (string, error) {
+	value, exists := c.Get(UserIDKey)
+	if !exists {
+		return "", errors.New("userID not found in context")
+	}
+
+	userID, ok := value.(string)
+	if !ok || userID == "" {
+		return "", errors.New("userID in context is not a string")
+	}
+
+	return userID, nil
+}
+
+// IsEmailVerified reports whether the authenticated user verified their email
+func IsEmailVerified(c *gin.Context) bool {
+	return c.GetBool(EmailVerifiedKey)
+}
+
+// HasAuthMethod reports whether the user logged in with the given amr value,
+// e.g. "mfa" for a login with a second factor
+func HasAuthMethod(c *gin.Context, method string) bool {
+	return slices.Contains(c.GetStringSlice(AuthMethodsKey), method)
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval limits how often an unknown kid triggers a refetch, so
+// tokens with made-up kids cannot flood the auth service
+const minRefreshInterval = 30 * time.Second
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// JWKSCache keeps the public keys published by the auth service. Keys are
+// refetched after ttl, or earlier when a token names a kid not seen yet, e.g.
+// right after a key rotation. The fetch runs without holding the lock, so a
+// slow auth service only delays the requests waiting for a key they lack
+type JWKSCache struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	// refreshing is the fetch in flight, nil when there is none
+	refreshing *jwksRefresh
+}
+
+// jwksRefresh is a fetch of the key set. done is closed once it is over and
+// err is set
+type jwksRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+func NewJWKSCache(url string, ttl time.Duration) *JWKSCache {
+	return &JWKSCache{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 5 * time.Second},
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+// Key returns the public key with the given kid. A known key is returned
+// right away, even while the key set is refetched because it is stale
+func (c *JWKSCache) Key(kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	now := time.Now()
+	key, ok := c.keys[kid]
+	stale := now.Sub(c.fetchedAt) > c.ttl
+
+	refresh := c.refreshing
+	if refresh == nil && (!ok || stale) && now.Sub(c.lastAttempt) >= minRefreshInterval {
+		c.lastAttempt = now
+		refresh = &jwksRefresh{done: make(chan struct{})}
+		c.refreshing = refresh
+		go c.refresh(refresh)
+	}
+	c.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+	if refresh == nil {
+		return nil, ErrUnknownKey
+	}
+
+	<-refresh.done
+	c.mu.Lock()
+	key, ok = c.keys[kid]
+	c.mu.Unlock()
+
+	if !ok {
+		if refresh.err != nil {
+			return nil, refresh.err
+		}
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// refresh fetches the key set and swaps it in. On failure the keys we have
+// are kept, the auth service may be briefly unavailable
+func (c *JWKSCache) refresh(refresh *jwksRefresh) {
+	keys, err := c.fetch()
+
+	c.mu.Lock()
+	if err == nil {
+		c.keys = keys
+		c.fetchedAt = time.Now()
+	}
+	c.refreshing = nil
+	c.mu.Unlock()
+
+	refresh.err = err
+	close(refresh.done)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (c *JWKSCache) fetch() (map[string]*rsa.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || k.Kid == "" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer publishes the keys by kid. Once gate is set, requests wait for
+// it to be closed
+type jwksServer struct {
+	keys     map[string]*rsa.PublicKey
+	gate     chan struct{}
+	requests atomic.Int32
+	started  chan struct{}
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s.requests.Add(1)
+	if s.gate != nil {
+		s.started <- struct{}{}
+		<-s.gate
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, key := range s.keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+func TestJWKSCacheFetchDoesNotBlockKnownKeys(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	server := &jwksServer{keys: map[string]*rsa.PublicKey{"old": &private.PublicKey}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	cache := NewJWKSCache(ts.URL, time.Hour)
+	if _, err := cache.Key("old"); err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+
+	// the auth service rotates its key and then answers slowly
+	server.keys = map[string]*rsa.PublicKey{"old": &private.PublicKey, "new": &private.PublicKey}
+	server.gate = make(chan struct{})
+	server.started = make(chan struct{}, 1)
+	cache.mu.Lock()
+	cache.lastAttempt = time.Time{}
+	cache.mu.Unlock()
+
+	const waiting = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, waiting)
+	for range waiting {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.Key("new")
+			errs <- err
+		}()
+	}
+	<-server.started
+
+	known := make(chan error, 1)
+	go func() {
+		_, err := cache.Key("old")
+		known <- err
+	}()
+	select {
+	case err := <-known:
+		if err != nil {
+			t.Fatalf("known key: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a known key waited for the fetch")
+	}
+
+	close(server.gate)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("new key: %v", err)
+		}
+	}
+	if got := server.requests.Load(); got != 2 {
+		t.Errorf("key set fetched %d times, want 2", got)
+	}
+}
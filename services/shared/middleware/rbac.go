@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roles and permissions granted by the auth service
+const (
+	RoleGuest = "guest"
+	RoleHost  = "host"
+	RoleAdmin = "admin"
+
+	PermissionCreateBooking    = "bookings:create"
+	PermissionCreateListing    = "listings:create"
+	PermissionModerateListings = "listings:moderate"
+	PermissionModerateProfiles = "profiles:moderate"
+)
+
+// RequireRole lets the request through if the user has one of the roles. It
+// must run after AuthMiddleware
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRoles := c.GetStringSlice(RolesKey)
+		for _, role := range roles {
+			if slices.Contains(userRoles, role) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient role", "required_roles": roles})
+		c.Abort()
+	}
+}
+
+// RequirePermission lets the request through if the roles of the user grant
+// the permission. It must run after AuthMiddleware
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission", "required_permission": permission})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail lets the request through if the user verified their
+// email. It must run after AuthMiddleware
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsEmailVerified(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Email address is not verified"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func HasPermission(c *gin.Context, permission string) bool {
+	return slices.Contains(c.GetStringSlice(PermissionsKey), permission)
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		permissions []string
+		want        int
+	}{
+		{name: "granted", permissions: []string{PermissionCreateBooking}, want: http.StatusCreated},
+		{name: "other permission", permissions: []string{"listings:create"}, want: http.StatusForbidden},
+		{name: "no permissions", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/booking", func(c *gin.Context) {
+				c.Set(PermissionsKey, tt.permissions)
+			}, RequirePermission(PermissionCreateBooking), func(c *gin.Context) {
+				c.Status(http.StatusCreated)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/booking", nil))
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const TokenTypeAccess = "access"
+
+var (
+	ErrTokenMalformed       = errors.New("token is malformed")
+	ErrTokenSignature       = errors.New("token signature is invalid")
+	ErrTokenExpired         = errors.New("token has expired")
+	ErrTokenNotYetValid     = errors.New("token is not valid yet")
+	ErrTokenMissingClaim    = errors.New("token is missing a required claim")
+	ErrTokenInvalidIssuer   = errors.New("token issuer is not accepted")
+	ErrTokenInvalidAudience = errors.New("token audience is not accepted")
+	ErrTokenWrongType       = errors.New("token is not an access token")
+	ErrTokenRevoked         = errors.New("token is no longer active")
+	ErrIntrospection        = errors.New("token could not be introspected")
+)
+
+// Introspector asks the auth service whether a token that passed the local
+// checks is still active
+type Introspector interface {
+	TokenActive(ctx context.Context, token string) (bool, error)
+}
+
+// Claims are the claims of an access token issued by the auth service
+type Claims struct {
+	UserID string `json:"user_id"`
+	Type   string `json:"typ"`
+	// EmailVerified tells whether the user confirmed their email address
+	EmailVerified bool `json:"email_verified"`
+	// AuthMethods is the amr claim, e.g. ["pwd", "otp", "mfa"] after a login
+	// with a second factor
+	AuthMethods []string `json:"amr"`
+	// Roles of the user and the Permissions they grant
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	jwt.StandardClaims
+}
+
+// TokenValidator checks access tokens issued by the auth service. Only RS256
+// with a key from the JWKS is accepted, and exp, iat, iss, aud and typ are
+// required. Time checks tolerate clockSkew
+type TokenValidator struct {
+	keys      *JWKSCache
+	issuer    string
+	audience  string
+	clockSkew time.Duration
+	parser    *jwt.Parser
+	// introspector is optional, see UseIntrospector
+	introspector Introspector
+}
+
+func NewTokenValidator(keys *JWKSCache, issuer string, audience string, clockSkew time.Duration) *TokenValidator {
+	return &TokenValidator{
+		keys:      keys,
+		issuer:    issuer,
+		audience:  audience,
+		clockSkew: clockSkew,
+		// time claims are checked below, with the clock skew applied
+		parser: &jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg()}, SkipClaimsValidation: true},
+	}
+}
+
+// UseIntrospector makes Validate also ask the auth service about every token,
+// so that revoked sessions and deleted users are turned away before their
+// tokens expire
+func (v *TokenValidator) UseIntrospector(introspector Introspector) {
+	v.introspector = introspector
+}
+
+// Validate returns the claims of a valid access token. Errors wrap one of the
+// ErrToken values, or ErrIntrospection if the auth service could not be asked
+func (v *TokenValidator) Validate(ctx context.Context, tokenString string) (*Claims, error) {
+	var claims Claims
+	_, err := v.parser.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, ErrTokenMalformed
+		}
+		return v.keys.Key(kid)
+	})
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0 {
+			return nil, ErrTokenSignature
+		}
+		return nil, fmt.Errorf("%w: %v", ErrTokenMalformed, err)
+	}
+
+	if err := v.validateClaims(&claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if v.introspector != nil {
+		active, err := v.introspector.TokenActive(ctx, tokenString)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrIntrospection, err)
+		}
+		if !active {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	return &claims, nil
+}
+
+func (v *TokenValidator) validateClaims(claims *Claims, now time.Time) error {
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("%w: exp", ErrTokenMissingClaim)
+	}
+	if claims.IssuedAt == 0 {
+		return fmt.Errorf("%w: iat", ErrTokenMissingClaim)
+	}
+	if claims.UserID == "" {
+		return fmt.Errorf("%w: user_id", ErrTokenMissingClaim)
+	}
+
+	if !now.Add(-v.clockSkew).Before(time.Unix(claims.ExpiresAt, 0)) {
+		return ErrTokenExpired
+	}
+	if now.Add(v.clockSkew).Before(time.Unix(claims.IssuedAt, 0)) {
+		return ErrTokenNotYetValid
+	}
+	if claims.NotBefore != 0 && now.Add(v.clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return ErrTokenNotYetValid
+	}
+
+	if claims.Issuer != v.issuer {
+		return ErrTokenInvalidIssuer
+	}
+	if claims.Audience != v.audience {
+		return ErrTokenInvalidAudience
+	}
+	if claims.Type != TokenTypeAccess {
+		return ErrTokenWrongType
+	}
+
+	return nil
+}
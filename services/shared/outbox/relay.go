@@ -0,0 +1,164 @@
+// Package outbox relays the events the services store in their outbox table
+// to the broker
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var (
+	pendingMessages = expvar.NewInt("outbox_pending_messages")
+	deadMessages    = expvar.NewInt("outbox_dead_messages")
+	lagSeconds      = expvar.NewFloat("outbox_lag_seconds")
+)
+
+// Message is an outbox row as the relay sees it
+type Message struct {
+	ID          string
+	AggregateID string
+	Payload     []byte
+	Attempts    int
+}
+
+type Stats struct {
+	// Pending excludes dead messages
+	Pending         int64
+	Dead            int64
+	OldestCreatedAt time.Time
+}
+
+type Store interface {
+	// FetchPendingOutbox returns unpublished messages that are due, oldest
+	// first. A message is left out while an older unpublished message of the
+	// same aggregate waits for its next attempt, so that it cannot overtake it
+	FetchPendingOutbox(limit int, now time.Time) ([]Message, error)
+	MarkOutboxPublished(id string) error
+	MarkOutboxFailed(id string, lastError string, nextAttemptAt time.Time) error
+	// MarkOutboxDead parks a message that can never be published
+	MarkOutboxDead(id string, lastError string) error
+	GetOutboxStats() (*Stats, error)
+}
+
+// Publisher publishes the events of type E, which the payloads decode to
+type Publisher[E any] interface {
+	Publish(ctx context.Context, event *E) error
+}
+
+type Config struct {
+	PollInterval   time.Duration
+	BatchSize      int
+	PublishTimeout time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Relay drains the outbox table to the event publisher. A message is marked as
+// published only after the broker acknowledged it, so delivery is at-least-once.
+// Messages of the same aggregate are published in the order they were stored
+type Relay[E any] struct {
+	store     Store
+	publisher Publisher[E]
+	cfg       Config
+	log       *slog.Logger
+}
+
+func NewRelay[E any](store Store, publisher Publisher[E], cfg Config, log *slog.Logger) *Relay[E] {
+	return &Relay[E]{store: store, publisher: publisher, cfg: cfg, log: log}
+}
+
+func (r *Relay[E]) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.drain(ctx)
+		r.reportLag()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay[E]) drain(ctx context.Context) {
+	const fn = "outbox.drain"
+	log := r.log.With(slog.String("fn", fn))
+
+	messages, err := r.store.FetchPendingOutbox(r.cfg.BatchSize, time.Now())
+	if err != nil {
+		log.Error("failed to fetch outbox messages", slog.String("error", err.Error()))
+		return
+	}
+
+	// aggregates whose oldest message failed in this batch; their later
+	// messages wait for it
+	blocked := make(map[string]bool)
+	for i := range messages {
+		msg := &messages[i]
+		if blocked[msg.AggregateID] {
+			continue
+		}
+
+		var event E
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			log.Error("outbox message cannot be decoded, parking it", slog.String("error", err.Error()), slog.String("id", msg.ID))
+			if err := r.store.MarkOutboxDead(msg.ID, err.Error()); err != nil {
+				log.Error("failed to park outbox message", slog.String("error", err.Error()), slog.String("id", msg.ID))
+				blocked[msg.AggregateID] = true
+			}
+			continue
+		}
+
+		if err := r.publish(ctx, &event); err != nil {
+			log.Error("failed to publish outbox message", slog.String("error", err.Error()),
+				slog.String("id", msg.ID), slog.Int("attempts", msg.Attempts+1))
+			if err := r.store.MarkOutboxFailed(msg.ID, err.Error(), time.Now().Add(r.backoff(msg.Attempts))); err != nil {
+				log.Error("failed to reschedule outbox message", slog.String("error", err.Error()))
+			}
+			blocked[msg.AggregateID] = true
+			continue
+		}
+
+		if err := r.store.MarkOutboxPublished(msg.ID); err != nil {
+			log.Error("failed to mark outbox message as published", slog.String("error", err.Error()), slog.String("id", msg.ID))
+			blocked[msg.AggregateID] = true
+		}
+	}
+}
+
+func (r *Relay[E]) publish(ctx context.Context, event *E) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.PublishTimeout)
+	defer cancel()
+
+	if err := r.publisher.Publish(ctx, event); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	return nil
+}
+
+func (r *Relay[E]) backoff(attempts int) time.Duration {
+	delay := r.cfg.PollInterval << min(attempts, 16)
+	return min(delay, r.cfg.MaxBackoff)
+}
+
+func (r *Relay[E]) reportLag() {
+	stats, err := r.store.GetOutboxStats()
+	if err != nil {
+		r.log.Error("failed to get outbox stats", slog.String("error", err.Error()))
+		return
+	}
+
+	pendingMessages.Set(stats.Pending)
+	deadMessages.Set(stats.Dead)
+	if stats.Pending == 0 {
+		lagSeconds.Set(0)
+		return
+	}
+	lagSeconds.Set(time.Since(stats.OldestCreatedAt).Seconds())
+}
@@ -0,0 +1,98 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type event struct {
+	Name string `json:"name"`
+}
+
+// fakeStore hands out msgs once and records what the relay did with them
+type fakeStore struct {
+	msgs   []Message
+	events []string
+}
+
+func (s *fakeStore) FetchPendingOutbox(limit int, _ time.Time) ([]Message, error) {
+	return s.msgs[:min(limit, len(s.msgs))], nil
+}
+
+func (s *fakeStore) MarkOutboxPublished(id string) error {
+	s.events = append(s.events, "published "+id)
+	return nil
+}
+
+func (s *fakeStore) MarkOutboxFailed(id string, _ string, _ time.Time) error {
+	s.events = append(s.events, "failed "+id)
+	return nil
+}
+
+func (s *fakeStore) MarkOutboxDead(id string, _ string) error {
+	s.events = append(s.events, "dead "+id)
+	return nil
+}
+
+func (s *fakeStore) GetOutboxStats() (*Stats, error) {
+	return &Stats{}, nil
+}
+
+// fakePublisher fails the events named in fail
+type fakePublisher struct {
+	fail      map[string]bool
+	published []string
+}
+
+func (p *fakePublisher) Publish(_ context.Context, e *event) error {
+	if p.fail[e.Name] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, e.Name)
+	return nil
+}
+
+func newTestRelay(store Store, publisher Publisher[event]) *Relay[event] {
+	cfg := Config{PollInterval: time.Second, BatchSize: 10, PublishTimeout: time.Second, MaxBackoff: time.Minute}
+	return NewRelay[event](store, publisher, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestDrainBlocksAggregateBehindFailedMessage(t *testing.T) {
+	store := &fakeStore{msgs: []Message{
+		{ID: "1", AggregateID: "a", Payload: []byte(`{"name":"a1"}`)},
+		{ID: "2", AggregateID: "b", Payload: []byte(`{"name":"b1"}`)},
+		{ID: "3", AggregateID: "a", Payload: []byte(`{"name":"a2"}`)},
+	}}
+	publisher := &fakePublisher{fail: map[string]bool{"a1": true}}
+
+	newTestRelay(store, publisher).drain(context.Background())
+
+	if want := []string{"b1"}; !reflect.DeepEqual(publisher.published, want) {
+		t.Errorf("published %v, want %v", publisher.published, want)
+	}
+	if want := []string{"failed 1", "published 2"}; !reflect.DeepEqual(store.events, want) {
+		t.Errorf("store events %v, want %v", store.events, want)
+	}
+}
+
+func TestDrainParksMalformedPayload(t *testing.T) {
+	store := &fakeStore{msgs: []Message{
+		{ID: "1", AggregateID: "a", Payload: []byte(`{"name":`)},
+		{ID: "2", AggregateID: "a", Payload: []byte(`{"name":"a2"}`)},
+	}}
+	publisher := &fakePublisher{}
+
+	newTestRelay(store, publisher).drain(context.Background())
+
+	if want := []string{"a2"}; !reflect.DeepEqual(publisher.published, want) {
+		t.Errorf("published %v, want %v", publisher.published, want)
+	}
+	if want := []string{"dead 1", "published 2"}; !reflect.DeepEqual(store.events, want) {
+		t.Errorf("store events %v, want %v", store.events, want)
+	}
+}
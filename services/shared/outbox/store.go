@@ -0,0 +1,106 @@
+package outbox
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// table is where the services store their outbox messages. Its columns are
+// those of the OutboxMessage entity of every service
+const table = "outbox"
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore is the Store on the outbox table of a service database
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) FetchPendingOutbox(limit int, now time.Time) ([]Message, error) {
+	const fn = "outbox.FetchPendingOutbox"
+	var messages []Message
+
+	result := s.db.Table(table).
+		Select("id, aggregate_id, payload, attempts").
+		Where("published_at IS NULL AND dead_at IS NULL AND next_attempt_at <= ?", now).
+		Where(`NOT EXISTS (SELECT 1 FROM `+table+` older
+			WHERE older.aggregate_id = `+table+`.aggregate_id
+			AND older.published_at IS NULL AND older.dead_at IS NULL AND older.next_attempt_at > ?
+			AND (older.created_at, older.id) < (`+table+`.created_at, `+table+`.id))`, now).
+		Order("created_at, id").Limit(limit).Find(&messages)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return messages, nil
+}
+
+func (s *gormStore) MarkOutboxPublished(id string) error {
+	const fn = "outbox.MarkOutboxPublished"
+
+	result := s.db.Table(table).Where("id = ?", id).Update("published_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return nil
+}
+
+func (s *gormStore) MarkOutboxFailed(id string, lastError string, nextAttemptAt time.Time) error {
+	const fn = "outbox.MarkOutboxFailed"
+
+	result := s.db.Table(table).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      lastError,
+		"next_attempt_at": nextAttemptAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return nil
+}
+
+func (s *gormStore) MarkOutboxDead(id string, lastError string) error {
+	const fn = "outbox.MarkOutboxDead"
+
+	result := s.db.Table(table).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": lastError,
+		"dead_at":    time.Now(),
+	})
+	if result.Error != nil {
+		return fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	return nil
+}
+
+func (s *gormStore) GetOutboxStats() (*Stats, error) {
+	const fn = "outbox.GetOutboxStats"
+	var row struct {
+		Pending int64
+		Dead    int64
+		Oldest  *time.Time
+	}
+
+	result := s.db.Table(table).
+		Select(`COUNT(*) FILTER (WHERE dead_at IS NULL) AS pending,
+			COUNT(*) FILTER (WHERE dead_at IS NOT NULL) AS dead,
+			MIN(created_at) FILTER (WHERE dead_at IS NULL) AS oldest`).
+		Where("published_at IS NULL").Scan(&row)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%s: database error: %w", fn, result.Error)
+	}
+
+	stats := &Stats{Pending: row.Pending, Dead: row.Dead}
+	if row.Oldest != nil {
+		stats.OldestCreatedAt = *row.Oldest
+	}
+
+	return stats, nil
+}
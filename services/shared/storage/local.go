@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type localStore struct {
+	dir       string
+	publicURL string
+}
+
+// NewLocalStore keeps blobs as files in dir. Their URLs are publicURL followed
+// by the key
+func NewLocalStore(dir string, publicURL string) (BlobStore, error) {
+	const fn = "storage.NewLocalStore"
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return &localStore{dir: dir, publicURL: strings.TrimSuffix(publicURL, "/")}, nil
+}
+
+func (s *localStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	const fn = "storage.local.Put"
+
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	// write to a temporary file first so readers never see a partial blob
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, key)); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *localStore) Get(ctx context.Context, key string) (*Blob, error) {
+	const fn = "storage.local.Get"
+
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	file, err := os.Open(filepath.Join(s.dir, key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, ErrNotFound
+	}
+
+	etag, err := contentHash(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return &Blob{
+		Body:        file,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(key)),
+		ModTime:     info.ModTime(),
+		ETag:        etag,
+	}, nil
+}
+
+// contentHash hashes the file and rewinds it. Blobs are small images, so
+// hashing on every read is cheaper than keeping the hash elsewhere
+func contentHash(file *os.File) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *localStore) Delete(ctx context.Context, key string) error {
+	const fn = "storage.local.Delete"
+
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	if err := os.Remove(filepath.Join(s.dir, key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+// SignedURL returns the public URL of the blob. Local files are served by the
+// service itself, so the URL does not expire
+func (s *localStore) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+
+	return s.publicURL + "/" + key, nil
+}
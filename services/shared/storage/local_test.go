@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var invalidKeys = []string{"", ".", "..", "../escape.webp", "dir/name.webp", `dir\name.webp`, "name\x00.webp", "/abs.webp"}
+
+// testRoundTrip puts a blob, reads it back and deletes it again
+func testRoundTrip(t *testing.T, store BlobStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	const key = "0f8fad5b-d9cb-469f-a165-70867728950e_full.webp"
+	content := []byte("RIFF not really a webp")
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "image/webp"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	blob, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	got, err := io.ReadAll(blob.Body)
+	blob.Body.Close()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(got, content) || blob.Size != int64(len(content)) {
+		t.Errorf("blob = %q (%d bytes), want %q", got, blob.Size, content)
+	}
+	if blob.ContentType != "image/webp" {
+		t.Errorf("content type = %q, want image/webp", blob.ContentType)
+	}
+	if blob.ETag == "" {
+		t.Error("blob has no etag")
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get after delete: got %v, want %v", err, ErrNotFound)
+	}
+	// deleting a missing blob is not an error, e.g. on a retried cleanup
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("second delete: %v", err)
+	}
+}
+
+// testInvalidKeys checks that every method refuses keys that are not a plain
+// file name
+func testInvalidKeys(t *testing.T, store BlobStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, key := range invalidKeys {
+		if err := store.Put(ctx, key, bytes.NewReader(nil), 0, "image/webp"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("put %q: got %v, want %v", key, err, ErrInvalidKey)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("get %q: got %v, want %v", key, err, ErrInvalidKey)
+		}
+		if err := store.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("delete %q: got %v, want %v", key, err, ErrInvalidKey)
+		}
+		if _, err := store.SignedURL(ctx, key, time.Minute); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("signed url %q: got %v, want %v", key, err, ErrInvalidKey)
+		}
+	}
+}
+
+func newTestLocalStore(t *testing.T) (BlobStore, string) {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "uploads")
+	store, err := NewLocalStore(dir, "/uploads/")
+	if err != nil {
+		t.Fatalf("new local store: %v", err)
+	}
+	return store, dir
+}
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	store, _ := newTestLocalStore(t)
+	testRoundTrip(t, store)
+}
+
+func TestLocalStoreInvalidKeys(t *testing.T) {
+	store, dir := newTestLocalStore(t)
+	testInvalidKeys(t, store)
+
+	entries, err := os.ReadDir(filepath.Dir(dir))
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("files next to the store: %v, want only the store", entries)
+	}
+}
+
+func TestLocalStoreBlob(t *testing.T) {
+	store, _ := newTestLocalStore(t)
+	ctx := context.Background()
+	content := []byte("not really a jpeg")
+
+	if err := store.Put(ctx, "photo.jpg", bytes.NewReader(content), int64(len(content)), "image/jpeg"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	blob, err := store.Get(ctx, "photo.jpg")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer blob.Body.Close()
+
+	hash := sha256.Sum256(content)
+	if want := hex.EncodeToString(hash[:]); blob.ETag != want {
+		t.Errorf("etag = %q, want the content hash %q", blob.ETag, want)
+	}
+	if blob.ContentType != "image/jpeg" {
+		t.Errorf("content type = %q, want image/jpeg", blob.ContentType)
+	}
+
+	url, err := store.SignedURL(ctx, "photo.jpg", time.Minute)
+	if err != nil {
+		t.Fatalf("signed url: %v", err)
+	}
+	if url != "/uploads/photo.jpg" {
+		t.Errorf("url = %q, want /uploads/photo.jpg", url)
+	}
+}
+
+func TestLocalStoreGetMissing(t *testing.T) {
+	store, _ := newTestLocalStore(t)
+
+	if _, err := store.Get(context.Background(), "missing.webp"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type s3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Store keeps blobs in an S3-compatible bucket, creating it when missing.
+// Any S3 API works, MinIO included
+func NewS3Store(ctx context.Context, cfg S3Config) (BlobStore, error) {
+	const fn = "storage.NewS3Store"
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	return &s3Store{client: client, bucket: cfg.Bucket}, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	const fn = "storage.s3.Put"
+
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (*Blob, error) {
+	const fn = "storage.s3.Get"
+
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	// GetObject is lazy, Stat performs the request and reports a missing key
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return &Blob{
+		Body:        object,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		ModTime:     info.LastModified,
+		ETag:        strings.Trim(info.ETag, `"`),
+	}, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	const fn = "storage.s3.Delete"
+
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *s3Store) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	const fn = "storage.s3.SignedURL"
+
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, ttl, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return u.String(), nil
+}
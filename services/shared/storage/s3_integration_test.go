@@ -0,0 +1,91 @@
+//go:build integration
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// newTestS3Store creates a bucket of its own on the S3 API in
+// TEST_S3_ENDPOINT, e.g. a local MinIO started with
+// "docker run -p 9000:9000 minio/minio server /data" and reached with
+// TEST_S3_ENDPOINT=localhost:9000 TEST_S3_ACCESS_KEY=minioadmin TEST_S3_SECRET_KEY=minioadmin.
+// Run with: go test -tags integration ./...
+func newTestS3Store(t *testing.T) BlobStore {
+	t.Helper()
+
+	endpoint := os.Getenv("TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("TEST_S3_ENDPOINT is not set")
+	}
+
+	ctx := context.Background()
+	store, err := NewS3Store(ctx, S3Config{
+		Endpoint:  endpoint,
+		AccessKey: os.Getenv("TEST_S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("TEST_S3_SECRET_KEY"),
+		Bucket:    fmt.Sprintf("storage-test-%d", time.Now().UnixNano()),
+		Region:    "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("new s3 store: %v", err)
+	}
+
+	s3 := store.(*s3Store)
+	t.Cleanup(func() {
+		for object := range s3.client.ListObjects(ctx, s3.bucket, minio.ListObjectsOptions{Recursive: true}) {
+			s3.client.RemoveObject(ctx, s3.bucket, object.Key, minio.RemoveObjectOptions{})
+		}
+		if err := s3.client.RemoveBucket(ctx, s3.bucket); err != nil {
+			t.Errorf("remove bucket: %v", err)
+		}
+	})
+
+	return store
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	testRoundTrip(t, newTestS3Store(t))
+}
+
+func TestS3StoreInvalidKeys(t *testing.T) {
+	testInvalidKeys(t, newTestS3Store(t))
+}
+
+func TestS3StoreSignedURL(t *testing.T) {
+	store := newTestS3Store(t)
+	ctx := context.Background()
+	content := []byte("not really a webp")
+
+	if err := store.Put(ctx, "photo.webp", bytes.NewReader(content), int64(len(content)), "image/webp"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	url, err := store.SignedURL(ctx, "photo.webp", time.Minute)
+	if err != nil {
+		t.Fatalf("signed url: %v", err)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("fetch signed url: %v", err)
+	}
+	defer resp.Body.Close()
+
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(got, content) {
+		t.Errorf("signed url answered %d %q, want 200 %q", resp.StatusCode, got, content)
+	}
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImmutableCacheControl is used for public images. Every upload gets a new
+// file name, so a given URL never changes content
+const ImmutableCacheControl = "public, max-age=31536000, immutable"
+
+var imageNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,254}$`)
+
+// ValidImageName accepts plain file names only, which rules out path traversal
+func ValidImageName(name string) bool {
+	return imageNamePattern.MatchString(name) && !strings.Contains(name, "..")
+}
+
+// ServeBlob writes the blob with its validators. http.ServeContent answers
+// conditional requests (If-None-Match, If-Modified-Since) and Range requests
+func ServeBlob(ctx *gin.Context, blob *Blob, cacheControl string) {
+	header := ctx.Writer.Header()
+	if blob.ETag != "" {
+		header.Set("ETag", `"`+blob.ETag+`"`)
+	}
+	if blob.ContentType != "" {
+		header.Set("Content-Type", blob.ContentType)
+	}
+	header.Set("Cache-Control", cacheControl)
+	header.Set("X-Content-Type-Options", "nosniff")
+
+	http.ServeContent(ctx.Writer, ctx.Request, "", blob.ModTime, blob.Body)
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestServeBlob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	content := []byte("not really a webp")
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        int
+		wantBody    string
+	}{
+		{name: "full", want: http.StatusOK, wantBody: string(content)},
+		{name: "cached", ifNoneMatch: `"abc"`, want: http.StatusNotModified},
+		{name: "stale", ifNoneMatch: `"old"`, want: http.StatusOK, wantBody: string(content)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/images/:filename", func(c *gin.Context) {
+				ServeBlob(c, &Blob{Body: nopCloser{bytes.NewReader(content)}, Size: int64(len(content)),
+					ContentType: "image/webp", ModTime: time.Now(), ETag: "abc"}, ImmutableCacheControl)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/images/a.webp", nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want || w.Body.String() != tt.wantBody {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), tt.want, tt.wantBody)
+			}
+			if got := w.Header().Get("ETag"); got != `"abc"` {
+				t.Errorf("etag = %q, want %q", got, `"abc"`)
+			}
+			if got := w.Header().Get("Cache-Control"); got != ImmutableCacheControl {
+				t.Errorf("cache control = %q, want %q", got, ImmutableCacheControl)
+			}
+		})
+	}
+}
+
+func TestValidImageName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "0f8fad5b-d9cb-469f-a165-70867728950e_full.webp", want: true},
+		{name: "photo.jpg", want: true},
+		{name: ""},
+		{name: ".hidden"},
+		{name: "a..b.webp"},
+		{name: "../escape.webp"},
+		{name: "dir/name.webp"},
+	}
+
+	for _, tt := range tests {
+		if got := ValidImageName(tt.name); got != tt.want {
+			t.Errorf("ValidImageName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
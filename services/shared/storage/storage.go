@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobStore keeps uploaded files under flat keys such as "<id>_full.webp"
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (*Blob, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL the client can fetch the blob from for at least ttl
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Blob is an open blob. The caller must close Body
+type Blob struct {
+	Body        io.ReadSeekCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+	// ETag is a strong validator of the content, without quotes
+	ETag string
+}
+
+// Config selects and configures the backend
+type Config struct {
+	// Backend is BackendLocal or BackendS3
+	Backend   string
+	LocalDir  string
+	PublicURL string
+	S3        S3Config
+}
+
+// S3Config configures an S3-compatible bucket
+type S3Config struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	UseSSL    bool
+}
+
+// New returns the backend selected in the config
+func New(ctx context.Context, cfg Config) (BlobStore, error) {
+	const fn = "storage.New"
+
+	switch cfg.Backend {
+	case BackendLocal, "":
+		return NewLocalStore(cfg.LocalDir, cfg.PublicURL)
+	case BackendS3:
+		return NewS3Store(ctx, cfg.S3)
+	default:
+		return nil, fmt.Errorf("%s: unknown storage backend %q", fn, cfg.Backend)
+	}
+}
+
+// validKey rejects anything that is not a single plain path segment, so a key
+// can never escape the store
+func validKey(key string) bool {
+	if key == "" || key == "." || key == ".." {
+		return false
+	}
+	if strings.ContainsAny(key, "/\\\x00") {
+		return false
+	}
+	return path.Base(key) == key
+}